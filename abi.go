@@ -0,0 +1,159 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+)
+
+var (
+	abiKeys = struct {
+		sync.RWMutex
+		byName map[string]DataKey
+		byKey  map[DataKey]string
+	}{byName: make(map[string]DataKey), byKey: make(map[DataKey]string)}
+)
+
+// ClassID returns a stable integer identifier for the class, suitable for
+// passing across a C ABI. It is a hash of the class' Path, so it is the same
+// in every process and build that declares the class the same way. 0 is
+// never a class ID; ExportABI uses it for errors that have no class.
+// Creating (or renaming) a class so that two paths share an ID panics with a
+// ProgrammerError, so IDs always find the one class they were made from.
+func ClassID(class *ErrorClass) uint32 {
+	if class == nil {
+		return 0
+	}
+	return pathID(class.canonical().Path())
+}
+
+// pathID hashes a class path into a ClassID.
+func pathID(path string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	id := h.Sum32()
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// ClassForID returns the registered class with the given ClassID, the one
+// LookupClass finds if several classes share its path, or nil if there is
+// none. IDs of the former paths of classes moved by Rename and Reparent find
+// the classes too, as their paths do in LookupClass.
+func ClassForID(id uint32) *ErrorClass {
+	if id == 0 {
+		return nil
+	}
+	classes := Classes()
+	for i := len(classes) - 1; i >= 0; i-- {
+		if ClassID(classes[i]) == id {
+			return classes[i].canonical()
+		}
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for path, class := range aliases {
+		if pathID(path) == id {
+			return class.canonical()
+		}
+	}
+	return nil
+}
+
+// ExportDataKey gives a data key a stable name, so ExportABI includes values
+// stored under it in the data buffer and ImportABI restores them. Values must
+// encode to JSON; after crossing the ABI they come back as the generic JSON
+// types (float64, string, map[string]interface{} and so on).
+func ExportDataKey(name string, key DataKey) {
+	abiKeys.Lock()
+	defer abiKeys.Unlock()
+	abiKeys.byName[name] = key
+	abiKeys.byKey[key] = name
+}
+
+// ExportABI flattens err into the parts that can cross a C ABI: the class ID
+// of its outermost class (0 if it has none), its message without the class
+// name, and a JSON object holding the data stored under keys named with
+// ExportDataKey. Stacks and exits are not exported. A Go core exposed as a
+// shared library can return these through cgo with C.uint32_t, C.CString and
+// C.CBytes, and ImportABI rebuilds an equivalent error from them.
+func ExportABI(err error) (class uint32, message string, data []byte,
+	exportErr error) {
+	travel(err, JourneySerialized, 2)
+	return exportABI(Export(err))
+}
+
+func exportABI(x ExportedError) (class uint32, message string, data []byte,
+	exportErr error) {
+	if x.err == nil {
+		return 0, "", nil, nil
+	}
+	if _, ok := x.err.(*Error); !ok {
+		return 0, x.err.Error(), nil, nil
+	}
+	class = ClassID(x.Class())
+	message = x.innerMessage()
+
+	values := make(map[string]interface{})
+	abiKeys.RLock()
+	for key, name := range abiKeys.byKey {
+		if val := x.GetData(key); val != nil {
+			values[name] = val
+		}
+	}
+	abiKeys.RUnlock()
+	if len(values) == 0 {
+		return class, message, nil, nil
+	}
+	data, exportErr = json.Marshal(values)
+	return class, message, data, exportErr
+}
+
+// ImportABI rebuilds an error from the parts produced by ExportABI. If the
+// class ID isn't registered in this process, the error belongs to
+// HierarchicalError. Data under names unknown to ExportDataKey is dropped.
+func ImportABI(class uint32, message string, data []byte) (error, error) {
+	return importABI(class, message, data, 2)
+}
+
+// importABI is ImportABI, for a caller depth callers up.
+func importABI(class uint32, message string, data []byte,
+	depth int) (error, error) {
+	ec := ClassForID(class)
+	if ec == nil {
+		ec = HierarchicalError
+	}
+	var options []ErrorOption
+	if len(data) > 0 {
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		abiKeys.RLock()
+		for name, val := range values {
+			if key, ok := abiKeys.byName[name]; ok {
+				options = append(options, SetData(key, val))
+			}
+		}
+		abiKeys.RUnlock()
+	}
+	rv := ec.NewWith(message, options...)
+	travel(rv, JourneyDeserialized, depth+1)
+	return rv, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+type creationSite struct {
+	class *ErrorClass
+	pc    uintptr
+}
+
+// maxCreationSites bounds how many creation sites Adaptivestacks tracks.
+const maxCreationSites = 4096
+
+var (
+	// siteCounts interns a counter per creationSite for Adaptivestacks, and
+	// sites counts its entries.
+	siteCounts sync.Map
+	sites      int64
+)
+
+// novelSite reports whether an error of class created skip frames up from
+// the caller should have its stack captured under Config.Adaptivestacks:
+// the first Adaptivestacks errors of each class from each creation site do,
+// and the rest skip the (much more expensive) full capture, so novel
+// failures keep full detail while known noisy paths stay cheap. Once
+// maxCreationSites sites are tracked, counting starts over, so a process
+// creating classes dynamically doesn't grow the counts without bound.
+func novelSite(class *ErrorClass, skip int) bool {
+	limit := Config.Adaptivestacks
+	if limit <= 0 {
+		return true
+	}
+	var pc [1]uintptr
+	if runtime.Callers(skip, pc[:]) == 0 {
+		return true
+	}
+	site := creationSite{class: class, pc: pc[0]}
+	count, ok := siteCounts.Load(site)
+	if !ok {
+		if atomic.AddInt64(&sites, 1) > maxCreationSites {
+			siteCounts.Clear()
+			atomic.StoreInt64(&sites, 1)
+		}
+		count, ok = siteCounts.LoadOrStore(site, new(int64))
+		if ok {
+			atomic.AddInt64(&sites, -1)
+		}
+	}
+	return atomic.AddInt64(count.(*int64), 1) <= int64(limit)
+}
+
+// forgetSites drops the Adaptivestacks counts of the classes owned reports,
+// for Scope.Close and Plugin.Unload.
+func forgetSites(owned func(*ErrorClass) bool) {
+	siteCounts.Range(func(site, _ interface{}) bool {
+		if owned(site.(creationSite).class) {
+			siteCounts.Delete(site)
+			atomic.AddInt64(&sites, -1)
+		}
+		return true
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"sync"
+)
+
+var (
+	// classBits hands out the bits of classes: next is the lowest never
+	// handed out (the roots have 0 and 1), and free holds the bits of
+	// classes that were unregistered and then garbage collected, for reuse,
+	// so ancestry sets stay as small as the set of live classes allows.
+	classBits = struct {
+		sync.Mutex
+		next int
+		free []int
+	}{next: 2}
+)
+
+// setAncestry gives a newly registered class its bit (shared with the class
+// it's interned to, if any) and its ancestry set.
+func (e *ErrorClass) setAncestry() {
+	if canon := e.canonical(); canon != e {
+		e.bit = canon.bit
+	} else {
+		e.bit = allocBit()
+	}
+	size := len(e.parent.ancestry)
+	if e.bit/64 >= size {
+		size = e.bit/64 + 1
+	}
+	e.ancestry = make([]uint64, size)
+	copy(e.ancestry, e.parent.ancestry)
+	e.ancestry[e.bit/64] |= 1 << uint(e.bit%64)
+}
+
+// allocBit returns the lowest free bit, or a new one.
+func allocBit() int {
+	classBits.Lock()
+	defer classBits.Unlock()
+	if len(classBits.free) == 0 {
+		classBits.next++
+		return classBits.next - 1
+	}
+	lowest := 0
+	for i, bit := range classBits.free {
+		if bit < classBits.free[lowest] {
+			lowest = i
+		}
+	}
+	bit := classBits.free[lowest]
+	last := len(classBits.free) - 1
+	classBits.free[lowest] = classBits.free[last]
+	classBits.free = classBits.free[:last]
+	return bit
+}
+
+// reclaimBit arranges for the bit of a class just removed from the registry
+// to be reused once the class is garbage collected. Until then errors of
+// the class, and its descendents (which keep it alive through their
+// parents), still have the bit in their ancestry sets. Classes sharing the
+// bit of the class they're interned to leave it alone; they keep that class
+// alive too. It must be called before the class is tombstoned.
+func reclaimBit(class *ErrorClass) {
+	if class.canonical() != class {
+		return
+	}
+	runtime.AddCleanup(class, func(bit int) {
+		classBits.Lock()
+		classBits.free = append(classBits.free, bit)
+		classBits.Unlock()
+	}, class.bit)
+}
+
+// descends is Is, answered from the ancestry sets: the class' own, or that
+// of the class it's interned or tombstoned to.
+func (e *ErrorClass) descends(parent *ErrorClass) bool {
+	bit := parent.canonical().bit
+	if hasBit(e.ancestry, bit) {
+		return true
+	}
+	canon := e.canonical()
+	return canon != e && hasBit(canon.ancestry, bit)
+}
+
+func hasBit(set []uint64, bit int) bool {
+	return bit/64 < len(set) && set[bit/64]&(1<<uint(bit%64)) != 0
+}
+
+// IsAny reports whether the class of err, as GetClass reports it, is or
+// descends from any of classes. It's for code routing errors by class
+// without a try plan, and costs a few bit tests per class rather than a walk
+// up the hierarchy.
+func IsAny(err error, classes ...*ErrorClass) bool {
+	return MatchingClass(err, classes...) != nil
+}
+
+// MatchingClass returns the first of classes that the class of err, as
+// GetClass reports it, is or descends from, or nil if there is none.
+func MatchingClass(err error, classes ...*ErrorClass) *ErrorClass {
+	class := GetClass(err)
+	if class == nil {
+		return nil
+	}
+	for _, c := range classes {
+		if c != nil && class.descends(c) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var (
+	// AssertionError is raised by Assert and AssertEqual when an internal
+	// invariant doesn't hold.
+	AssertionError = ProgrammerError.NewClass("Assertion Error")
+
+	// ExpectedKey and ActualKey hold the values compared by a failed
+	// AssertEqual.
+	ExpectedKey = GenSym()
+	ActualKey   = GenSym()
+)
+
+// Assert panics with an AssertionError with the given message if cond is
+// false. It is meant for internal invariant checks that try blocks or
+// CatchPanic can catch and report.
+func Assert(cond bool, format string, args ...interface{}) {
+	if !cond {
+		panic(AssertionError.New(format, args...))
+	}
+}
+
+// AssertEqual panics with an AssertionError if expected and actual are not
+// deeply equal. The values are attached to the error under ExpectedKey and
+// ActualKey.
+func AssertEqual(expected, actual interface{}) {
+	if reflect.DeepEqual(expected, actual) {
+		return
+	}
+	panic(AssertionError.NewWith(
+		fmt.Sprintf("expected %#v, got %#v", expected, actual),
+		SetData(ExpectedKey, expected), SetData(ActualKey, actual)))
+}
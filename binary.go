@@ -0,0 +1,221 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package errors
+
+import (
+	"encoding/binary"
+)
+
+// The binary encoding is a FlatBuffer of the Error table in binary.fbs, so
+// other languages can read it with code generated by flatc. EncodeBinary
+// always lays it out the same way: the root table offset and the file
+// identifier, then the vtable, the table, and the strings in field order,
+// omitting empty ones. Readers follow the offsets, so they don't rely on the
+// layout, and ignore fields added to the end of the table later.
+const (
+	binaryIdentifier = "SMEB"
+	binaryVtable     = 8
+	binaryVtableSize = 4 + 2*int(binaryFields)
+	binaryTable      = binaryVtable + binaryVtableSize
+	binaryTableSize  = 4 + 4*int(binaryFields)
+)
+
+// binaryField is the index of a field of the Error table, in binary.fbs
+// order.
+type binaryField int
+
+const (
+	binaryClassID binaryField = iota
+	binaryPath
+	binaryMessage
+	binaryStack
+	binaryExits
+	binaryData
+	binaryFields
+)
+
+var (
+	// BinaryFormatError is returned when parsing malformed binary encodings.
+	BinaryFormatError = NewClass("Binary Format Error")
+)
+
+// EncodeBinary serializes err as a FlatBuffer (see binary.fbs) whose fields
+// can be read in place, without decoding or copying, through a BinaryError
+// view, or by other languages with flatc's generated code. It holds the same
+// class ID, message and data as ExportABI, plus the class path, stack and
+// exits.
+func EncodeBinary(err error) ([]byte, error) {
+	travel(err, JourneySerialized, 2)
+	x := Export(err)
+	class, message, data, eerr := exportABI(x)
+	if eerr != nil {
+		return nil, eerr
+	}
+	strs := [binaryFields][]byte{
+		binaryPath:    []byte(x.Class().Path()),
+		binaryMessage: []byte(message),
+		binaryStack:   []byte(x.Stack()),
+		binaryExits:   []byte(x.Exits()),
+		binaryData:    data,
+	}
+	size := binaryTable + binaryTableSize
+	for _, str := range strs {
+		if len(str) > 0 {
+			size += (4 + len(str) + 1 + 3) &^ 3
+		}
+	}
+	le := binary.LittleEndian
+	buf := make([]byte, binaryTable+binaryTableSize, size)
+	le.PutUint32(buf, uint32(binaryTable))
+	copy(buf[4:], binaryIdentifier)
+	le.PutUint16(buf[binaryVtable:], uint16(binaryVtableSize))
+	le.PutUint16(buf[binaryVtable+2:], uint16(binaryTableSize))
+	le.PutUint32(buf[binaryTable:], uint32(binaryTable-binaryVtable))
+	for f := binaryClassID; f < binaryFields; f++ {
+		slot := 4 + 4*int(f)
+		if f != binaryClassID && len(strs[f]) == 0 {
+			continue
+		}
+		le.PutUint16(buf[binaryVtable+4+2*int(f):], uint16(slot))
+		if f == binaryClassID {
+			le.PutUint32(buf[binaryTable+slot:], class)
+			continue
+		}
+		le.PutUint32(buf[binaryTable+slot:], uint32(len(buf)-binaryTable-slot))
+		buf = le.AppendUint32(buf, uint32(len(strs[f])))
+		buf = append(buf, strs[f]...)
+		// strings are NUL terminated, and the next one is 4-byte aligned.
+		buf = append(buf, make([]byte, 4-len(buf)%4)...)
+	}
+	return buf, nil
+}
+
+// BinaryError is a read-only view of an error encoded by EncodeBinary. Its
+// accessors return slices of the underlying buffer, so they must not be
+// modified, and are only valid as long as the buffer is.
+type BinaryError []byte
+
+// ParseBinary checks that buf holds a well-formed FlatBuffer of an Error
+// table, with the file identifier EncodeBinary writes, and returns a view of
+// it. It doesn't copy buf.
+func ParseBinary(buf []byte) (BinaryError, error) {
+	if len(buf) < 8 || string(buf[4:8]) != binaryIdentifier {
+		return nil, BinaryFormatError.New("not a binary encoded error")
+	}
+	le := binary.LittleEndian
+	size := int64(len(buf))
+	table := int64(le.Uint32(buf))
+	if table%4 != 0 || table+4 > size {
+		return nil, BinaryFormatError.New("table out of bounds")
+	}
+	vtable := table - int64(int32(le.Uint32(buf[table:])))
+	if vtable < 0 || vtable%2 != 0 || vtable+4 > size {
+		return nil, BinaryFormatError.New("vtable out of bounds")
+	}
+	vsize := int64(le.Uint16(buf[vtable:]))
+	tsize := int64(le.Uint16(buf[vtable+2:]))
+	if vsize < 4 || vsize%2 != 0 || vtable+vsize > size ||
+		tsize < 4 || table+tsize > size {
+		return nil, BinaryFormatError.New("malformed vtable")
+	}
+	for f := binaryClassID; f < binaryFields && 4+2*int64(f) < vsize; f++ {
+		slot := int64(le.Uint16(buf[vtable+4+2*int64(f):]))
+		if slot == 0 {
+			continue
+		}
+		pos := table + slot
+		if slot < 4 || slot+4 > tsize || pos%4 != 0 {
+			return nil, BinaryFormatError.New("field %d out of bounds", f)
+		}
+		if f == binaryClassID {
+			continue
+		}
+		str := pos + int64(le.Uint32(buf[pos:]))
+		if str%4 != 0 || str+4 > size {
+			return nil, BinaryFormatError.New("field %d out of bounds", f)
+		}
+		end := str + 4 + int64(le.Uint32(buf[str:]))
+		if end >= size || buf[end] != 0 {
+			return nil, BinaryFormatError.New("field %d out of bounds", f)
+		}
+	}
+	return BinaryError(buf), nil
+}
+
+// field returns the position of the given field, or 0 if the encoding
+// doesn't have it.
+func (b BinaryError) field(f binaryField) int {
+	le := binary.LittleEndian
+	table := int(le.Uint32(b))
+	vtable := table - int(int32(le.Uint32(b[table:])))
+	if 4+2*int(f) >= int(le.Uint16(b[vtable:])) {
+		return 0
+	}
+	slot := int(le.Uint16(b[vtable+4+2*int(f):]))
+	if slot == 0 {
+		return 0
+	}
+	return table + slot
+}
+
+// str returns the bytes of the given string field, or nil if the encoding
+// doesn't have it.
+func (b BinaryError) str(f binaryField) []byte {
+	pos := b.field(f)
+	if pos == 0 {
+		return nil
+	}
+	str := pos + int(binary.LittleEndian.Uint32(b[pos:]))
+	end := str + 4 + int(binary.LittleEndian.Uint32(b[str:]))
+	return b[str+4 : end : end]
+}
+
+// ClassID returns the ClassID of the encoded error's class.
+func (b BinaryError) ClassID() uint32 {
+	pos := b.field(binaryClassID)
+	if pos == 0 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b[pos:])
+}
+
+// Path returns the encoded error's class path.
+func (b BinaryError) Path() []byte { return b.str(binaryPath) }
+
+// Message returns the encoded error's message, without its class name.
+func (b BinaryError) Message() []byte { return b.str(binaryMessage) }
+
+// Stack returns the encoded error's rendered stack.
+func (b BinaryError) Stack() []byte { return b.str(binaryStack) }
+
+// Exits returns the encoded error's rendered exits.
+func (b BinaryError) Exits() []byte { return b.str(binaryExits) }
+
+// Data returns the encoded error's data, as the JSON object ExportABI makes.
+func (b BinaryError) Data() []byte { return b.str(binaryData) }
+
+// Rehydrate rebuilds an error from the view, with the same class resolution
+// and data handling as ImportABI. If the class ID isn't registered, the class
+// is looked up by path before falling back to HierarchicalError.
+func (b BinaryError) Rehydrate() (error, error) {
+	id := b.ClassID()
+	if ClassForID(id) == nil {
+		if class := LookupClass(string(b.Path())); class != nil {
+			id = ClassID(class)
+		}
+	}
+	return importABI(id, string(b.Message()), b.Data(), 2)
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// An ErrorBudget accounts errors against the request they happened in, so
+// SLO tooling can keep per-request error budgets and escalate requests with
+// too many soft failures. Its methods may be called concurrently.
+type ErrorBudget interface {
+	// Created is called for each error created or wrapped by NewCtx or
+	// WrapCtx.
+	Created(request string, err error)
+	// Caught is called for each error passed to CaughtCtx, as try plans
+	// made with try.DoCtx do for errors their handlers consume.
+	Caught(request string, err error)
+}
+
+type budgetHook struct {
+	budget   ErrorBudget
+	identify func(ctx context.Context) string
+}
+
+var budget atomic.Pointer[budgetHook]
+
+// SetErrorBudget installs the ErrorBudget, and the function that finds the
+// identity of the request a context belongs to. Errors in contexts with no
+// request identity (where identify returns "") aren't accounted. Passing a
+// nil budget removes it. It panics with a ProgrammerError if b is set but
+// identify is nil.
+func SetErrorBudget(b ErrorBudget, identify func(ctx context.Context) string) {
+	if b == nil {
+		budget.Store(nil)
+		return
+	}
+	if identify == nil {
+		panic(ProgrammerError.New("SetErrorBudget needs an identify function"))
+	}
+	budget.Store(&budgetHook{budget: b, identify: identify})
+}
+
+// account reports an error to the ErrorBudget, if there is one.
+func account(ctx context.Context, err error, caught bool) {
+	hook := budget.Load()
+	if hook == nil || ctx == nil || err == nil {
+		return
+	}
+	request := hook.identify(ctx)
+	if request == "" {
+		return
+	}
+	if caught {
+		hook.budget.Caught(request, err)
+	} else {
+		hook.budget.Created(request, err)
+	}
+}
+
+// CaughtCtx is CaughtBy for handlers that know the context of the request
+// the error happened in: it also reports the error to the ErrorBudget.
+// handler may be empty.
+func CaughtCtx(ctx context.Context, err error, handler string) {
+	CaughtBy(err, handler)
+	account(ctx, err, true)
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	buildInfoKey = GenSym()
+
+	buildInfoOnce sync.Once
+	buildInfo     *BuildInfo
+)
+
+// BuildInfo describes the binary an error was created in. It is gathered once
+// from runtime/debug.ReadBuildInfo.
+type BuildInfo struct {
+	Path      string
+	Version   string
+	GoVersion string
+	Revision  string
+	Time      string
+	Modified  bool
+}
+
+// currentBuildInfo returns the BuildInfo for the running binary, or nil if
+// the binary was built without module support.
+func currentBuildInfo() *BuildInfo {
+	buildInfoOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		bi := &BuildInfo{
+			Path:      info.Main.Path,
+			Version:   info.Main.Version,
+			GoVersion: info.GoVersion}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				bi.Revision = setting.Value
+			case "vcs.time":
+				bi.Time = setting.Value
+			case "vcs.modified":
+				bi.Modified = setting.Value == "true"
+			}
+		}
+		buildInfo = bi
+	})
+	return buildInfo
+}
+
+// StampBuildInfo tells the error class and its descendents to carry the
+// running binary's BuildInfo as data, so error reports collected from many
+// deployed versions can be attributed. See GetBuildInfo.
+func StampBuildInfo() ErrorOption {
+	return SetData(buildInfoKey, currentBuildInfo())
+}
+
+// GetBuildInfo returns the BuildInfo stamped on the error by StampBuildInfo,
+// or nil if there is none.
+func GetBuildInfo(err error) *BuildInfo {
+	bi, _ := GetData(err, buildInfoKey).(*BuildInfo)
+	return bi
+}
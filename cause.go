@@ -0,0 +1,47 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+)
+
+// causedError is the message of an error made by NewCause, along with the
+// cause it describes.
+type causedError struct {
+	message string
+	cause   error
+}
+
+func (e *causedError) Error() string {
+	return e.message + ": " + GetMessage(e.cause)
+}
+
+func (e *causedError) Unwrap() error { return e.cause }
+
+// NewCause makes a new error of the class with a formatted message that
+// describes cause, which is kept, so FindType, RootCause and Contains with
+// IncludeWrapped still find it. The error's message
+// is the formatted message followed by the cause's. It's equivalent to New
+// if cause is nil.
+func (e *ErrorClass) NewCause(cause error, format string,
+	args ...interface{}) error {
+	if cause == nil {
+		return e.wrap(fmt.Errorf(format, args...), nil, nil)
+	}
+	return e.wrap(&causedError{
+		message: fmt.Sprintf(format, args...),
+		cause:   cause}, nil, nil)
+}
@@ -0,0 +1,28 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Emit sends err on ch, first recording the caller's location as an exit on
+// the error, so the error's journey between goroutines stays traceable.
+func Emit(ch chan<- error, err error) {
+	ch <- record(err, 3, "sent on channel")
+}
+
+// Receive receives an error from ch, recording the caller's location as an
+// exit on the error. ok is false if ch was closed, as with a normal receive.
+func Receive(ch <-chan error) (err error, ok bool) {
+	err, ok = <-ch
+	return record(err, 3, "received from channel"), ok
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+)
+
+// A Classifier determines the error class of errors not created through this
+// package, for GetClass and Contains. It returns nil for errors it doesn't
+// recognize.
+type Classifier func(err error) *ErrorClass
+
+var classifiers struct {
+	mu   sync.RWMutex
+	list []Classifier
+}
+
+// RegisterClassifier adds a Classifier to consult for errors not created
+// through this package. Registered classifiers are consulted before the
+// built-in standard library mapping, most recently registered first, so they
+// can both recognize new errors and override the defaults.
+func RegisterClassifier(c Classifier) {
+	classifiers.mu.Lock()
+	defer classifiers.mu.Unlock()
+	classifiers.list = append(classifiers.list, c)
+}
+
+func runClassifiers(err error) *ErrorClass {
+	classifiers.mu.RLock()
+	defer classifiers.mu.RUnlock()
+	for i := len(classifiers.list) - 1; i >= 0; i-- {
+		if class := classifiers.list[i](err); class != nil {
+			return class
+		}
+	}
+	return nil
+}
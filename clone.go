@@ -0,0 +1,55 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Clone returns a copy of err that can be modified (with exit records,
+// AttachData and so on) without racing with or showing up in err, which
+// another goroutine may hold. The data map, stack and exits are copied; data
+// values themselves and the wrapped error are shared. The copy keeps err's
+// ID, since it still describes the same failure. Clone returns nil if err
+// isn't an *Error.
+func Clone(err error) *Error {
+	cast, ok := err.(*Error)
+	if !ok {
+		return nil
+	}
+	return cast.clone()
+}
+
+func (e *Error) clone() *Error {
+	e.checkLive()
+	rv := &Error{
+		id:        e.idNum(),
+		err:       e.err,
+		class:     e.class,
+		stack:     append([]frame(nil), e.stack...),
+		exits:     append([]exit(nil), e.exits...),
+		created:   e.created,
+		fakeStack: append([]string(nil), e.fakeStack...),
+		// a clone escaping captures its own stack.
+		stackDeferred: e.stackDeferred,
+		origin:        e.origin,
+	}
+	e.travelMu.Lock()
+	rv.travels = append([]JourneyEvent(nil), e.travels...)
+	e.travelMu.Unlock()
+	if e.data != nil {
+		rv.data = make(map[DataKey]interface{}, len(e.data))
+		for key, val := range e.data {
+			rv.data[key] = val
+		}
+	}
+	return rv
+}
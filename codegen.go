@@ -0,0 +1,131 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// DuplicateClassError is returned by GenerateClassRefs when the registry
+	// holds accidental duplicate classes.
+	DuplicateClassError = NewClass("Duplicate Class Error")
+)
+
+// GenerateClassRefs writes the source of a Go package named pkg (typically
+// "errclasses") declaring one variable per registered error class, so code
+// can reference classes by identifier instead of re-creating near-duplicates
+// of them in multiple packages. Since classes are registered at runtime, the
+// generator is a small program that imports every package declaring classes
+// and calls GenerateClassRefs; imports lists those same packages, which the
+// generated package imports for their side effects so the classes exist
+// when it looks them up.
+//
+// It fails with a DuplicateClassError if two distinct classes share a path,
+// or if two paths would produce the same identifier.
+func GenerateClassRefs(w io.Writer, pkg string, imports ...string) error {
+	return generateClassRefs(w, pkg, Classes(), imports)
+}
+
+func generateClassRefs(w io.Writer, pkg string, classes []*ErrorClass,
+	imports []string) error {
+	byPath := make(map[string][]*ErrorClass)
+	for _, class := range classes {
+		path := class.Path()
+		if class.canonical() != class {
+			continue
+		}
+		byPath[path] = append(byPath[path], class)
+	}
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var dups []string
+	idents := make(map[string]string)
+	for _, path := range paths {
+		if len(byPath[path]) > 1 {
+			dups = append(dups, fmt.Sprintf("%q declared %d times",
+				path, len(byPath[path])))
+		}
+		ident := classIdent(byPath[path][0])
+		if other, exists := idents[ident]; exists {
+			dups = append(dups, fmt.Sprintf("%q and %q both generate %s",
+				other, path, ident))
+			continue
+		}
+		idents[ident] = path
+	}
+	if len(dups) > 0 {
+		return DuplicateClassError.New("%s", strings.Join(dups, "; "))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by errors.GenerateClassRefs. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", pkg)
+	fmt.Fprintf(&buf, "\t%q\n", "github.com/spacemonkeygo/errors")
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "\t_ %q\n", imp)
+	}
+	fmt.Fprintf(&buf, ")\n\nvar (\n")
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "\t%s = errors.LookupClass(%s)\n",
+			classIdent(byPath[path][0]), strconv.Quote(path))
+	}
+	fmt.Fprintf(&buf, ")\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// classIdent turns a class into an exported Go identifier by joining the
+// words of its name and its ancestors' names, leaving out the namespace and
+// the implicit HierarchicalError root.
+func classIdent(class *ErrorClass) string {
+	var names []string
+	for c := class; c != nil && c != HierarchicalError; c = c.parent {
+		names = append([]string{c.name}, names...)
+	}
+	var ident []rune
+	upper := true
+	for _, r := range strings.Join(names, " ") {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		ident = append(ident, r)
+	}
+	if len(ident) == 0 || !unicode.IsLetter(ident[0]) {
+		ident = append([]rune("Class"), ident...)
+	}
+	return string(ident)
+}
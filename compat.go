@@ -0,0 +1,57 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"reflect"
+	"strings"
+)
+
+// foreignClass recognizes errors from other copies of this package, such as
+// the upstream github.com/spacemonkeygo/errors or a vendored fork, whose
+// *Error and *ErrorClass types are distinct from ours. Such errors have a
+// Class method returning a class with String and Parent methods; if the
+// names along that class' ancestry form the path of a class registered here,
+// the error belongs to it, so Is and Contains match across the copies.
+func foreignClass(err error) *ErrorClass {
+	class := reflect.ValueOf(err).MethodByName("Class")
+	if !class.IsValid() || class.Type().NumIn() != 0 ||
+		class.Type().NumOut() != 1 {
+		return nil
+	}
+	var names []string
+	for c := class.Call(nil)[0]; ; {
+		if c.Kind() == reflect.Ptr && c.IsNil() {
+			break
+		}
+		name, parent := c.MethodByName("String"), c.MethodByName("Parent")
+		if !name.IsValid() || !parent.IsValid() ||
+			name.Type().NumIn() != 0 || parent.Type().NumIn() != 0 ||
+			name.Type().NumOut() != 1 || parent.Type().NumOut() != 1 ||
+			name.Type().Out(0).Kind() != reflect.String ||
+			parent.Type().Out(0) != c.Type() {
+			return nil
+		}
+		names = append(names, name.Call(nil)[0].String())
+		c = parent.Call(nil)[0]
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return LookupClass(strings.Join(names, "/"))
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	componentKey = GenSym()
+)
+
+// Component returns an ErrorOption that attributes the error, or errors of
+// the class and its descendents, to an architectural component such as
+// "storage". Components let dashboards attribute failures even when the
+// class taxonomy is shared across components.
+func Component(name string) ErrorOption {
+	return SetData(componentKey, name)
+}
+
+// TagComponent attributes the error *errp to the component unless it
+// already has one, for use in a defer at a package boundary:
+//
+//	func (s *Store) Get(key string) (val []byte, err error) {
+//		defer errors.TagComponent("storage", &err)
+//		...
+//	}
+//
+// Only errors created through this package can be tagged.
+func TagComponent(name string, errp *error) {
+	if *errp == nil || GetComponent(*errp) != "" {
+		return
+	}
+	AttachData(*errp, componentKey, name)
+}
+
+// GetComponent returns the component the error is attributed to, or the
+// empty string. If several layers of a wrapped error are attributed, the
+// innermost one wins, since that's where the failure started.
+func GetComponent(err error) string {
+	var component string
+	walk(err, func(layer error) bool {
+		if name, ok := GetData(layer, componentKey).(string); ok && name != "" {
+			component = name
+		}
+		return false
+	})
+	return component
+}
@@ -14,11 +14,71 @@
 
 package errors
 
+import (
+	"log"
+	"time"
+)
+
 // Config is a configuration struct meant to be used with
-//   github.com/spacemonkeygo/flagfile/utils.Setup
-// but can be set independently.
+//
+//	github.com/spacemonkeygo/flagfile/utils.Setup
+//
+// but can be set independently, directly or with the Set functions below.
+// This package never registers flags itself; applications that want flags
+// for Config opt in by passing it to their flag setup.
+//
+// Config, LogMethod and the Set functions aren't synchronized: set them
+// during initialization, before errors are created or logged concurrently.
+// Tests changing them must not run in parallel with tests making errors.
 var Config = struct {
-	Stacklogsize int `default:"4096" usage:"the max stack trace byte length to log"`
+	Stacklogsize     int           `default:"4096" usage:"the max stack trace byte length to log"`
+	Stackcapturesize int           `default:"256" usage:"the max number of frames captured in an error's stack"`
+	Debugpool        bool          `default:"false" usage:"never reuse pooled errors, and panic on use after release"`
+	Logdedupwindow   time.Duration `default:"0" usage:"suppress repeated logs of the same error within this window"`
+	Adaptivestacks   int           `default:"0" usage:"capture stacks for only the first N errors of each class from each creation site (0 captures every stack)"`
+	Debugexport      bool          `default:"false" usage:"panic when an error is modified after being exported"`
+	Memorycap        int           `default:"0" usage:"soft cap on the approximate bytes retained by live errors; beyond it new errors skip their stacks and cut long data short (0 disables)"`
+	Checkraises      bool          `default:"false" usage:"report errors escaping boundaries that didn't declare their class with Raises"`
+	Catchfatal       bool          `default:"false" usage:"let CatchPanic turn runtime panics that are unsafe to recover from into errors instead of re-panicking"`
+	Journeys         bool          `default:"false" usage:"record where errors are serialized and deserialized in their journeys, and carry journeys in MarshalError's JSON"`
 }{
-	Stacklogsize: 4096,
+	Stacklogsize:     4096,
+	Stackcapturesize: 256,
+}
+
+// SetStackLogSize sets Config.Stacklogsize, the max byte length of the
+// stacks LogWithStack logs. Call it during initialization, as with Config.
+func SetStackLogSize(size int) {
+	Config.Stacklogsize = size
+}
+
+// SetStackCaptureSize sets Config.Stackcapturesize, the max number of frames
+// captured in an error's stack. Errors created deeper than that keep their
+// innermost frames. Call it during initialization, as with Config.
+func SetStackCaptureSize(frames int) {
+	Config.Stackcapturesize = frames
+}
+
+// SetLogger sets LogMethod, which everything this package logs goes
+// through. nil restores the default, log.Printf. Call it during
+// initialization, as with Config; to change where logs go later, give it a
+// function whose destination is synchronized instead.
+func SetLogger(logf func(format string, args ...interface{})) {
+	if logf == nil {
+		logf = log.Printf
+	}
+	LogMethod = logf
+}
+
+// stackBuffer returns the buffer to capture an error's stack into: pcs, or
+// as much of it or a larger one as Config.Stackcapturesize asks for.
+func stackBuffer(pcs *[256]uintptr) []uintptr {
+	size := Config.Stackcapturesize
+	switch {
+	case size <= 0 || size == len(pcs):
+		return pcs[:]
+	case size < len(pcs):
+		return pcs[:size]
+	}
+	return make([]uintptr, size)
 }
@@ -0,0 +1,63 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	// ConflictError is the class of failed compare-and-swap and optimistic
+	// concurrency checks: the write lost a race with another writer. Its
+	// policy maps it to HTTP 409 Conflict. Storage layers should raise (or
+	// subclass) it rather than define their own, so callers can handle
+	// conflicts from any of them alike.
+	ConflictError = NewClass("Conflict Error",
+		SetPolicy(Policy{HTTPStatus: 409})) // Conflict
+
+	// StaleVersionError is a ConflictError for writes made against a
+	// version that is no longer current, such as a failed If-Match
+	// precondition. Its policy maps it to HTTP 412 Precondition Failed.
+	StaleVersionError = ConflictError.NewClass("Stale Version Error",
+		SetPolicy(Policy{HTTPStatus: 412})) // Precondition Failed
+
+	// ExpectedVersionKey and ActualVersionKey hold the version a write
+	// expected and the version actually found, as set by Versions. They
+	// are exported (see ExportDataKey) as "expected_version" and
+	// "actual_version".
+	ExpectedVersionKey = GenSym()
+	ActualVersionKey   = GenSym()
+)
+
+func init() {
+	ExportDataKey("expected_version", ExpectedVersionKey)
+	ExportDataKey("actual_version", ActualVersionKey)
+}
+
+// Versions returns an ErrorOption recording the version a write expected
+// and the version it found:
+//
+//	errors.StaleVersionError.NewWith("updating "+key,
+//		errors.Versions(expected, current))
+func Versions(expected, actual interface{}) ErrorOption {
+	return func(data map[DataKey]interface{}) {
+		data[ExpectedVersionKey] = expected
+		data[ActualVersionKey] = actual
+	}
+}
+
+// GetVersions returns the versions recorded by Versions, and whether there
+// were any.
+func GetVersions(err error) (expected, actual interface{}, ok bool) {
+	expected = GetData(err, ExpectedVersionKey)
+	actual = GetData(err, ActualVersionKey)
+	return expected, actual, expected != nil || actual != nil
+}
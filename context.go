@@ -0,0 +1,184 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var (
+	traceKey = GenSym()
+
+	harvesters struct {
+		mu   sync.RWMutex
+		list []ContextHarvester
+	}
+
+	traceConfig struct {
+		mu        sync.RWMutex
+		extractor func(ctx context.Context) TraceInfo
+		baggage   map[string]bool
+	}
+)
+
+// A ContextHarvester returns options to apply to errors created or wrapped
+// with a context, such as through NewCtx or WrapCtx.
+type ContextHarvester func(ctx context.Context) []ErrorOption
+
+// RegisterContextHarvester adds a ContextHarvester that is consulted every
+// time an error is created or wrapped with a context.
+func RegisterContextHarvester(h ContextHarvester) {
+	harvesters.mu.Lock()
+	defer harvesters.mu.Unlock()
+	harvesters.list = append(harvesters.list, h)
+}
+
+// harvest collects the options all harvesters want applied for ctx.
+func harvest(ctx context.Context) (options []ErrorOption) {
+	if ctx == nil {
+		return nil
+	}
+	harvesters.mu.RLock()
+	defer harvesters.mu.RUnlock()
+	for _, h := range harvesters.list {
+		options = append(options, h(ctx)...)
+	}
+	if trace, ok := extractTrace(ctx); ok {
+		options = append(options, SetData(traceKey, trace))
+	}
+	return options
+}
+
+// NewCtx is like New, but the error also carries whatever the registered
+// ContextHarvesters and trace extractor pull out of ctx, and the class
+// overrides from WithClassOverrides apply.
+func (e *ErrorClass) NewCtx(ctx context.Context, format string,
+	args ...interface{}) error {
+	e = e.orRoot()
+	err := e.wrap(fmt.Errorf(format, args...), nil,
+		append(classOverrides(ctx, e), harvest(ctx)...))
+	account(ctx, err, false)
+	return err
+}
+
+// WrapCtx is like Wrap, but the error also carries whatever the registered
+// ContextHarvesters and trace extractor pull out of ctx, and the class
+// overrides from WithClassOverrides apply (before options, so options win).
+func (e *ErrorClass) WrapCtx(ctx context.Context, err error,
+	options ...ErrorOption) error {
+	e = e.orRoot()
+	options = append(classOverrides(ctx, e), options...)
+	rv := e.wrap(err, nil, append(options, harvest(ctx)...))
+	account(ctx, rv, false)
+	return rv
+}
+
+type overridesKey struct{}
+
+// classOverride is one WithClassOverrides call, linked to the ones made on
+// the contexts it was derived from.
+type classOverride struct {
+	outer   *classOverride
+	class   *ErrorClass
+	options []ErrorOption
+}
+
+// WithClassOverrides returns a context in which errors of class and its
+// descendents created with NewCtx or WrapCtx get options as if the class had
+// been declared with them, such as CaptureStack() for requests from a debug
+// tenant, or LogOnCreation() while a customer's requests are investigated.
+// Overrides set on derived contexts apply after, so win over, those set on
+// the contexts they were derived from.
+func WithClassOverrides(ctx context.Context, class *ErrorClass,
+	options ...ErrorOption) context.Context {
+	outer, _ := ctx.Value(overridesKey{}).(*classOverride)
+	return context.WithValue(ctx, overridesKey{}, &classOverride{
+		outer: outer, class: class, options: options})
+}
+
+// classOverrides collects the options ctx overrides class with, outermost
+// first.
+func classOverrides(ctx context.Context, class *ErrorClass) []ErrorOption {
+	if ctx == nil {
+		return nil
+	}
+	override, _ := ctx.Value(overridesKey{}).(*classOverride)
+	var matched []*classOverride
+	for ; override != nil; override = override.outer {
+		if class.Is(override.class) {
+			matched = append(matched, override)
+		}
+	}
+	var options []ErrorOption
+	for i := len(matched) - 1; i >= 0; i-- {
+		options = append(options, matched[i].options...)
+	}
+	return options
+}
+
+// TraceInfo identifies the trace and span an error was created in.
+type TraceInfo struct {
+	TraceID string
+	SpanID  string
+	Baggage map[string]string
+}
+
+// SetTraceExtractor installs the function used to find trace information in
+// the contexts given to NewCtx and WrapCtx. This keeps the package free of
+// tracing dependencies; an OpenTelemetry integration would read the span
+// context and baggage from ctx. Only baggage members named in baggageKeys
+// are kept on errors.
+func SetTraceExtractor(extractor func(ctx context.Context) TraceInfo,
+	baggageKeys ...string) {
+	traceConfig.mu.Lock()
+	defer traceConfig.mu.Unlock()
+	traceConfig.extractor = extractor
+	traceConfig.baggage = make(map[string]bool, len(baggageKeys))
+	for _, key := range baggageKeys {
+		traceConfig.baggage[key] = true
+	}
+}
+
+func extractTrace(ctx context.Context) (TraceInfo, bool) {
+	traceConfig.mu.RLock()
+	defer traceConfig.mu.RUnlock()
+	if traceConfig.extractor == nil {
+		return TraceInfo{}, false
+	}
+	trace := traceConfig.extractor(ctx)
+	if trace.TraceID == "" && trace.SpanID == "" && len(trace.Baggage) == 0 {
+		return TraceInfo{}, false
+	}
+	baggage := trace.Baggage
+	trace.Baggage = nil
+	for key, val := range baggage {
+		if traceConfig.baggage[key] {
+			if trace.Baggage == nil {
+				trace.Baggage = make(map[string]string)
+			}
+			trace.Baggage[key] = val
+		}
+	}
+	return trace, true
+}
+
+// GetTraceInfo returns the trace information harvested when the error was
+// created, if any.
+func GetTraceInfo(err error) (TraceInfo, bool) {
+	trace, ok := GetData(err, traceKey).(TraceInfo)
+	return trace, ok
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"context"
+)
+
+// ContextKey names a context value to copy into error data: the value
+// stored in the context under Context is stored in the error under Data.
+type ContextKey struct {
+	Context interface{}
+	Data    DataKey
+}
+
+// FromContextKeys returns an ErrorOption that copies the values of the
+// given keys in ctx, such as a request or user ID, into the error's data.
+// Keys missing from ctx are left unset. To copy the same keys into every
+// error made with NewCtx or WrapCtx, see RegisterContextKeys.
+func FromContextKeys(ctx context.Context, keys ...ContextKey) ErrorOption {
+	return func(data map[DataKey]interface{}) {
+		for _, key := range keys {
+			if val := ctx.Value(key.Context); val != nil {
+				data[key.Data] = val
+			}
+		}
+	}
+}
+
+// RegisterContextKeys has every error created or wrapped with a context,
+// through NewCtx or WrapCtx, copy the values of the given keys from its
+// context, so a service sets the policy once at startup.
+func RegisterContextKeys(keys ...ContextKey) {
+	RegisterContextHarvester(func(ctx context.Context) []ErrorOption {
+		return []ErrorOption{FromContextKeys(ctx, keys...)}
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync/atomic"
+)
+
+// ClassStats describes an error class and how often it has been seen, as
+// served by DebugHandler.
+type ClassStats struct {
+	Path          string `json:"path"`
+	Parent        string `json:"parent,omitempty"`
+	CaptureStack  bool   `json:"capture_stack"`
+	LogOnCreation bool   `json:"log_on_creation"`
+	Created       int64  `json:"created"`
+	Caught        int64  `json:"caught"`
+}
+
+// Stats returns the class' description and counters. Created counts errors
+// instantiated or wrapped by the class, and Caught counts errors of exactly
+// this class passed to Caught.
+func (e *ErrorClass) Stats() ClassStats {
+	if e == nil {
+		return ClassStats{}
+	}
+	stats := ClassStats{
+		Path:          e.Path(),
+		CaptureStack:  boolWrapper(e.data[captureStack], false),
+		LogOnCreation: boolWrapper(e.data[logOnCreation], false),
+		Created:       atomic.LoadInt64(&e.created),
+		Caught:        atomic.LoadInt64(&e.caught)}
+	if e.parent != nil {
+		stats.Parent = e.parent.Path()
+	}
+	return stats
+}
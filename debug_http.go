@@ -0,0 +1,40 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DebugHandler returns an http.Handler serving the class registry, each
+// class' settings and counters, and the current Config as JSON. It is meant
+// to be mounted somewhere like /debug/errors.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		classes := Classes()
+		stats := make([]ClassStats, 0, len(classes))
+		for _, class := range classes {
+			stats = append(stats, class.Stats())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Classes []ClassStats `json:"classes"`
+			Config  interface{}  `json:"config"`
+		}{Classes: stats, Config: Config})
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	// The following keys hold the parts of standard library errors like
+	// *os.PathError, *os.LinkError, *os.SyscallError and *net.OpError. Wrap
+	// fills them in from the error being wrapped (the outermost error that
+	// has a part wins), unless an option set them already. Read them with
+	// GetOp, GetPath, GetLinkTarget, GetAddr and GetErrno.
+	OpKey         = GenSym() // string: "open", "dial", ...
+	PathKey       = GenSym() // string: the path, or a LinkError's old name
+	LinkTargetKey = GenSym() // string: a LinkError's new name
+	AddrKey       = GenSym() // string: a net.OpError's remote address
+	ErrnoKey      = GenSym() // syscall.Errno
+
+	decomposedKeys = [...]DataKey{OpKey, PathKey, LinkTargetKey, AddrKey,
+		ErrnoKey}
+)
+
+// decompose copies the parts of the standard library errors wrapped by err
+// into data, without overwriting keys data already has.
+func decompose(err error, data map[DataKey]interface{}) map[DataKey]interface{} {
+	// keep New cheap: plain messages have nothing to decompose.
+	if !decomposable(err) && unwrapAll(err) == nil {
+		return data
+	}
+	walk(err, func(layer error) bool {
+		if _, ok := layer.(*Error); ok {
+			return false
+		}
+		for _, key := range decomposedKeys {
+			if _, ok := data[key]; ok {
+				continue
+			}
+			if part := layerPart(layer, key); part != nil {
+				if data == nil {
+					data = make(map[DataKey]interface{})
+				}
+				data[key] = part
+			}
+		}
+		return false
+	})
+	return data
+}
+
+// part finds the outermost part stored under key by err or the errors it
+// wraps, whether or not they were wrapped by this package.
+func part(err error, key DataKey) interface{} {
+	var found interface{}
+	walk(err, func(layer error) bool {
+		if cast, ok := layer.(*Error); ok {
+			found = cast.GetData(key)
+		} else {
+			found = layerPart(layer, key)
+		}
+		return found != nil
+	})
+	return found
+}
+
+// GetOp returns the operation that failed, such as "open" or "dial", from a
+// standard library error wrapped by err, or the empty string.
+func GetOp(err error) string {
+	op, _ := part(err, OpKey).(string)
+	return op
+}
+
+// GetPath returns the path a standard library file operation wrapped by err
+// failed on (the old name, for links and renames), or the empty string.
+func GetPath(err error) string {
+	path, _ := part(err, PathKey).(string)
+	return path
+}
+
+// GetLinkTarget returns the new name from an *os.LinkError wrapped by err, or
+// the empty string.
+func GetLinkTarget(err error) string {
+	target, _ := part(err, LinkTargetKey).(string)
+	return target
+}
+
+// GetAddr returns the remote address from a *net.OpError wrapped by err, or
+// the empty string.
+func GetAddr(err error) string {
+	addr, _ := part(err, AddrKey).(string)
+	return addr
+}
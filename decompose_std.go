@@ -0,0 +1,81 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// decomposable reports whether err itself has parts.
+func decomposable(err error) bool {
+	switch err.(type) {
+	case *os.PathError, *os.LinkError, *os.SyscallError, *net.OpError,
+		syscall.Errno:
+		return true
+	}
+	return false
+}
+
+// layerPart returns the part of a single standard library error stored under
+// key, or nil.
+func layerPart(err error, key DataKey) interface{} {
+	switch cast := err.(type) {
+	case *os.PathError:
+		switch key {
+		case OpKey:
+			return cast.Op
+		case PathKey:
+			return cast.Path
+		}
+	case *os.LinkError:
+		switch key {
+		case OpKey:
+			return cast.Op
+		case PathKey:
+			return cast.Old
+		case LinkTargetKey:
+			return cast.New
+		}
+	case *os.SyscallError:
+		if key == OpKey {
+			return cast.Syscall
+		}
+	case *net.OpError:
+		switch key {
+		case OpKey:
+			return cast.Op
+		case AddrKey:
+			if cast.Addr != nil {
+				return cast.Addr.String()
+			}
+		}
+	case syscall.Errno:
+		if key == ErrnoKey {
+			return cast
+		}
+	}
+	return nil
+}
+
+// GetErrno returns the syscall.Errno underlying err, and whether there was
+// one.
+func GetErrno(err error) (syscall.Errno, bool) {
+	errno, ok := part(err, ErrnoKey).(syscall.Errno)
+	return errno, ok
+}
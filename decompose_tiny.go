@@ -0,0 +1,28 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tinygo || errors_tiny
+
+package errors
+
+// decomposable is false in the reduced build, which has no os, net or
+// syscall errors to decompose.
+func decomposable(err error) bool {
+	return false
+}
+
+// layerPart likewise finds nothing.
+func layerPart(err error, key DataKey) interface{} {
+	return nil
+}
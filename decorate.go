@@ -0,0 +1,81 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"reflect"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// WrapAllReturns decorates fn, which must be a function whose last result is
+// an error, so that every non-nil error it returns is wrapped in class. It
+// lets a package declare that every error leaving a layer belongs to one
+// class without wrapping at each return site:
+//
+//	var Get = errors.WrapAllReturns(StorageError, get)
+//
+//	func get(key string) ([]byte, error) { ... }
+//
+// WrapAllReturns works through reflection, so calls cost more than calling fn
+// directly; WrapFunc, WrapFunc1 and WrapFunc1R cover common signatures
+// without that cost. It panics if fn isn't a function returning an error.
+func WrapAllReturns[F any](class *ErrorClass, fn F) F {
+	val := reflect.ValueOf(fn)
+	typ := val.Type()
+	if typ.Kind() != reflect.Func || typ.NumOut() == 0 ||
+		typ.Out(typ.NumOut()-1) != errorType || val.IsNil() {
+		panic(ProgrammerError.New(
+			"WrapAllReturns needs a function returning an error, not %s", typ))
+	}
+	last := typ.NumOut() - 1
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if typ.IsVariadic() {
+			results = val.CallSlice(args)
+		} else {
+			results = val.Call(args)
+		}
+		if err, _ := results[last].Interface().(error); err != nil {
+			results[last] = reflect.ValueOf(class.Wrap(err))
+		}
+		return results
+	}).Interface().(F)
+}
+
+// WrapFunc decorates fn so a non-nil error it returns is wrapped in class.
+func WrapFunc(class *ErrorClass, fn func() error) func() error {
+	return func() error {
+		return class.Wrap(fn())
+	}
+}
+
+// WrapFunc1 decorates fn so a non-nil error it returns is wrapped in class.
+func WrapFunc1[A any](class *ErrorClass, fn func(A) error) func(A) error {
+	return func(a A) error {
+		return class.Wrap(fn(a))
+	}
+}
+
+// WrapFunc1R decorates fn so a non-nil error it returns is wrapped in class.
+func WrapFunc1R[A, R any](class *ErrorClass,
+	fn func(A) (R, error)) func(A) (R, error) {
+	return func(a A) (R, error) {
+		r, err := fn(a)
+		return r, class.Wrap(err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxDedupEntries bounds how many distinct messages are remembered. Past it,
+// expired ones are pruned, and if none are, the oldest is forgotten.
+const maxDedupEntries = 1024
+
+type dedupEntry struct {
+	first      time.Time
+	suppressed int
+}
+
+var dedup = struct {
+	sync.Mutex
+	seen map[string]*dedupEntry
+}{seen: make(map[string]*dedupEntry)}
+
+// dedupKey identifies a set of log messages: a lone error by its class and
+// message (so repeats of the same failure match, without the dedup state
+// keeping errors alive), anything else by its text.
+func dedupKey(messages []interface{}) string {
+	if len(messages) == 1 {
+		if err, ok := messages[0].(*Error); ok {
+			return err.Class().Path() + "\x00" + err.Message()
+		}
+	}
+	return fmt.Sprint(messages...)
+}
+
+// shouldLog decides whether messages should be logged now, given
+// Config.Logdedupwindow. If they should, it also returns how many times they
+// were suppressed since they were last logged.
+func shouldLog(messages []interface{}) (ok bool, suppressed int) {
+	window := Config.Logdedupwindow
+	if window <= 0 {
+		return true, 0
+	}
+	key := dedupKey(messages)
+	now := time.Now()
+	dedup.Lock()
+	defer dedup.Unlock()
+	if entry, exists := dedup.seen[key]; exists {
+		if now.Sub(entry.first) < window {
+			entry.suppressed++
+			return false, 0
+		}
+		suppressed = entry.suppressed
+	}
+	if len(dedup.seen) >= maxDedupEntries {
+		var oldest string
+		for k, entry := range dedup.seen {
+			if now.Sub(entry.first) >= window {
+				delete(dedup.seen, k)
+			} else if oldest == "" ||
+				entry.first.Before(dedup.seen[oldest].first) {
+				oldest = k
+			}
+		}
+		if len(dedup.seen) >= maxDedupEntries {
+			delete(dedup.seen, oldest)
+		}
+	}
+	dedup.seen[key] = &dedupEntry{first: now}
+	return true, suppressed
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+)
+
+// deferredError is the message of an error made by DeferredNew, formatted
+// the first time it's asked for. err is the deferredError whose message this
+// one scrubs, if any, kept for inspection through Unwrap as scrubbedError
+// keeps its original.
+type deferredError struct {
+	once    sync.Once
+	format  func() string
+	message string
+	err     error
+}
+
+func (e *deferredError) Error() string {
+	e.once.Do(func() {
+		e.message = e.format()
+		e.format = nil
+	})
+	return e.message
+}
+
+func (e *deferredError) Unwrap() error { return e.err }
+
+// DeferredNew makes a new error of the class whose message is only formatted,
+// by calling format, if and when the message is needed: when the error is
+// printed, logged, journaled or otherwise rendered. format is called at most
+// once, possibly long after DeferredNew returns and from another goroutine,
+// so it must not depend on state that changes in the meantime. Use it when
+// building the message is expensive, such as serializing a request, and most
+// such errors are caught and discarded:
+//
+//	return ValidationError.DeferredNew(func() string {
+//		return fmt.Sprintf("invalid request %s", dumpRequest(req))
+//	})
+func (e *ErrorClass) DeferredNew(format func() string) error {
+	return e.wrap(&deferredError{format: format}, nil, nil)
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"encoding/json"
+	"io"
+)
+
+var (
+	messageTemplateKey = GenSym()
+	messageFormatKey   = GenSym()
+)
+
+// MessageTemplate documents the template messages of the class and its
+// descendents follow, such as "used {{.Used}} of {{.Limit}}". It only
+// describes the class for DescribeClasses; TypedClass sets it for you.
+func MessageTemplate(tmpl string) ErrorOption {
+	return SetData(messageTemplateKey, tmpl)
+}
+
+// MessageFormat is MessageTemplate for messages rendered with a printf-style
+// format, such as "quota exceeded: need %d". Define sets it for you.
+func MessageFormat(format string) ErrorOption {
+	return SetData(messageFormatKey, format)
+}
+
+// ClassDescriptor is a machine-readable description of an error class, for
+// generating client SDK enums so clients can switch on stable identifiers
+// (and render their own translated messages) instead of parsing ours.
+type ClassDescriptor struct {
+	// ID is the class' ClassID.
+	ID uint32 `json:"id"`
+	// Code is an identifier for the class, as used by GenerateClassRefs.
+	Code       string `json:"code"`
+	Path       string `json:"path"`
+	Parent     string `json:"parent,omitempty"`
+	UserFacing bool   `json:"user_facing"`
+	// Template is the class' MessageTemplate, and Format its MessageFormat.
+	// They are only included for UserFacing classes.
+	Template   string `json:"template,omitempty"`
+	Format     string `json:"format,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	// Hint is the class' remediation hint, from SetHint.
+	Hint string `json:"hint,omitempty"`
+}
+
+// DescribeClasses returns descriptors for every registered error class, in
+// creation order. Classes interned by Namespace, or sharing a path with an
+// earlier class, are only described once.
+func DescribeClasses() []ClassDescriptor {
+	var rv []ClassDescriptor
+	seen := make(map[string]bool)
+	for _, class := range Classes() {
+		path := class.Path()
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		desc := ClassDescriptor{
+			ID:         ClassID(class),
+			Code:       classIdent(class),
+			Path:       path,
+			UserFacing: boolWrapper(class.data[userFacing], false),
+			HTTPStatus: class.Policy().HTTPStatus}
+		desc.Hint, _ = class.data[hintKey].(string)
+		if class.parent != nil {
+			desc.Parent = class.parent.Path()
+		}
+		if desc.UserFacing {
+			desc.Template, _ = class.data[messageTemplateKey].(string)
+			desc.Format, _ = class.data[messageFormatKey].(string)
+		}
+		rv = append(rv, desc)
+	}
+	return rv
+}
+
+// WriteClassDescriptors writes DescribeClasses to w as a JSON array.
+func WriteClassDescriptors(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(DescribeClasses())
+}
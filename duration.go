@@ -0,0 +1,45 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"time"
+)
+
+var (
+	durationKey = GenSym()
+)
+
+// RecordDuration notes on err how long the operation that failed with it had
+// been running, unless a duration was already recorded (by an inner
+// operation). try plans do this for errors passing through them, so reports
+// can say "failed after 2.3s" without per-call timers. It returns err.
+func RecordDuration(err error, d time.Duration) error {
+	cast, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	if _, ok := cast.data[durationKey]; !ok {
+		Annotate(cast, durationKey, d)
+	}
+	return err
+}
+
+// GetDuration returns the duration recorded on err by RecordDuration, and
+// whether there was one.
+func GetDuration(err error) (time.Duration, bool) {
+	d, ok := GetData(err, durationKey).(time.Duration)
+	return d, ok
+}
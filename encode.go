@@ -0,0 +1,132 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// EncodeBudgetError is returned by EncodeBounded when even the error's
+	// class path doesn't fit in the byte budget.
+	EncodeBudgetError = NewClass("Encode Budget Error")
+)
+
+// Parts of an error EncodeBounded may drop, in the order it drops them.
+const (
+	DroppedStack   = "stack"
+	DroppedExits   = "exits"
+	DroppedData    = "data"
+	DroppedMessage = "message"
+)
+
+// EncodedError is the JSON form of an error produced by EncodeBounded. Data
+// holds the values stored under keys named with ExportDataKey. Dropped lists
+// the parts that were left out (or, for the message, truncated) to fit the
+// byte budget.
+type EncodedError struct {
+	Class   string                 `json:"class"`
+	Message string                 `json:"message"`
+	Stack   []string               `json:"stack,omitempty"`
+	Exits   []string               `json:"exits,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Dropped []string               `json:"dropped,omitempty"`
+}
+
+// EncodeBounded serializes err as an EncodedError in at most maxBytes of
+// JSON, so errors sent in RPC payloads never blow the frame limit. If the
+// full encoding is too big it degrades progressively: first the stack is
+// dropped, then the exits, then the data, and finally the message is
+// truncated. It returns the encoding along with the parts that were dropped.
+func EncodeBounded(err error, maxBytes int) ([]byte, []string, error) {
+	x := Export(err)
+	enc := EncodedError{
+		Class:   x.Class().Path(),
+		Message: x.Message()}
+	if stack := x.Stack(); stack != "" {
+		enc.Stack = strings.Split(stack, "\n")
+	}
+	if exits := x.Exits(); exits != "" {
+		enc.Exits = strings.Split(exits, "\n")
+	}
+	if _, ok := err.(*Error); ok {
+		abiKeys.RLock()
+		for key, name := range abiKeys.byKey {
+			if val := x.GetData(key); val != nil {
+				if enc.Data == nil {
+					enc.Data = make(map[string]interface{})
+				}
+				enc.Data[name] = val
+			}
+		}
+		abiKeys.RUnlock()
+	}
+
+	drops := []struct {
+		name  string
+		unset func()
+		set   bool
+	}{
+		{DroppedStack, func() { enc.Stack = nil }, enc.Stack != nil},
+		{DroppedExits, func() { enc.Exits = nil }, enc.Exits != nil},
+		{DroppedData, func() { enc.Data = nil }, enc.Data != nil},
+	}
+	out, merr := json.Marshal(enc)
+	for _, drop := range drops {
+		if merr != nil || len(out) <= maxBytes {
+			return out, enc.Dropped, merr
+		}
+		if !drop.set {
+			continue
+		}
+		drop.unset()
+		enc.Dropped = append(enc.Dropped, drop.name)
+		out, merr = json.Marshal(enc)
+	}
+	if merr != nil || len(out) <= maxBytes {
+		return out, enc.Dropped, merr
+	}
+
+	enc.Dropped = append(enc.Dropped, DroppedMessage)
+	message := enc.Message
+	for {
+		out, merr = json.Marshal(enc)
+		if merr != nil || len(out) <= maxBytes {
+			return out, enc.Dropped, merr
+		}
+		if message == "" {
+			return nil, enc.Dropped, EncodeBudgetError.New(
+				"%s doesn't fit in %d bytes", enc.Class, maxBytes)
+		}
+		// escaping can make the message grow, so cut at least what's over.
+		cut := len(message) - (len(out) - maxBytes)
+		if cut >= len(message) {
+			cut = len(message) - 1
+		}
+		if cut < 0 {
+			cut = 0
+		}
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+		enc.Message = message + "..."
+		if message == "" {
+			enc.Message = ""
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errexit carries classed errors across process boundaries for command
+line tools, as an exit code plus a machine-readable trailer line on stderr.
+
+A tool built with this package ends with
+
+	errexit.Exit(err)
+
+and a parent process that spawned it can get the error back with
+
+	err := errexit.FromProcess(cmd.Run(), stderr.Bytes())
+
+which reconstructs the class (when it is registered in the parent too) and
+message instead of collapsing everything into "exit status 1".
+*/
+package errexit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// TrailerPrefix starts the stderr line carrying the serialized error.
+const TrailerPrefix = "errexit-trailer: "
+
+var (
+	exitCode = errors.GenSym()
+
+	// SubprocessError is the class of errors from subprocesses whose class
+	// isn't registered in this process.
+	SubprocessError = errors.NewClass("Subprocess Error")
+)
+
+// SetExitCode returns an ErrorOption (for use in ErrorClass creation or
+// error instantiation) that controls the process exit code used by Exit.
+func SetExitCode(code int) errors.ErrorOption {
+	return errors.SetData(exitCode, code)
+}
+
+// GetExitCode will return the exit code associated with an error, and
+// default_code if none is found.
+func GetExitCode(err error, default_code int) int {
+	code, ok := errors.GetData(err, exitCode).(int)
+	if ok {
+		return code
+	}
+	return default_code
+}
+
+type trailer struct {
+	Class    string `json:"class"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// WriteTrailer writes the machine-readable trailer line for err to w.
+func WriteTrailer(w io.Writer, err error) error {
+	line, merr := json.Marshal(trailer{
+		Class:    errors.GetClass(err).Path(),
+		Message:  errors.GetMessage(errors.WrappedErr(err)),
+		ExitCode: GetExitCode(err, 1)})
+	if merr != nil {
+		return merr
+	}
+	_, werr := fmt.Fprintf(w, "\n%s%s\n", TrailerPrefix, line)
+	return werr
+}
+
+// Exit ends the process. If err is nil it exits with status 0; otherwise it
+// prints err and its trailer to stderr and exits with the error's exit code
+// (1 by default).
+func Exit(err error) {
+	if err == nil {
+		os.Exit(0)
+	}
+	fmt.Fprintln(os.Stderr, err)
+	WriteTrailer(os.Stderr, err)
+	os.Exit(GetExitCode(err, 1))
+}
+
+// ParseTrailer finds the last trailer in a subprocess' stderr output and
+// reconstructs its error: of the same class if that class is registered in
+// this process (see errors.LookupClass), and a SubprocessError otherwise. ok
+// is false if there was no trailer.
+func ParseTrailer(stderr []byte) (err error, ok bool) {
+	idx := bytes.LastIndex(stderr, []byte(TrailerPrefix))
+	if idx < 0 {
+		return nil, false
+	}
+	line := stderr[idx+len(TrailerPrefix):]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	var t trailer
+	if jerr := json.Unmarshal(line, &t); jerr != nil {
+		return nil, false
+	}
+	class := errors.LookupClass(t.Class)
+	if class == nil {
+		return SubprocessError.NewWith(fmt.Sprintf("%s: %s", t.Class, t.Message),
+			SetExitCode(t.ExitCode)), true
+	}
+	return class.NewWith(t.Message, SetExitCode(t.ExitCode)), true
+}
+
+// FromProcess converts the result of running a subprocess (such as the
+// error from exec.Cmd.Run) and its captured stderr into a classed error. If
+// the subprocess wrote a trailer, its error is reconstructed; otherwise
+// runErr is returned as is.
+func FromProcess(runErr error, stderr []byte) error {
+	if runErr == nil {
+		return nil
+	}
+	if _, ok := runErr.(*exec.ExitError); !ok {
+		return runErr
+	}
+	if err, ok := ParseTrailer(stderr); ok {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errexit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+func TestTrailerRoundTrip(t *testing.T) {
+	// scoped, so the class path is free again for the next run.
+	scope := errors.NewScope()
+	defer scope.Close()
+	UsageError := scope.NewClass(nil, "Usage Error", SetExitCode(2))
+	var stderr bytes.Buffer
+	stderr.WriteString("some earlier output\n")
+	err := WriteTrailer(&stderr, UsageError.New("missing --config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, ok := ParseTrailer(stderr.Bytes())
+	if !ok {
+		t.Fatalf("no trailer found in %q", stderr.String())
+	}
+	if !UsageError.Contains(parsed) {
+		t.Fatalf("expected a Usage Error, got %v", parsed)
+	}
+	if msg := errors.GetMessage(parsed); msg != "Usage Error: missing --config" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if code := GetExitCode(parsed, 1); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+
+	_, ok = ParseTrailer([]byte("no trailer here"))
+	if ok {
+		t.Fatal("found a trailer that isn't there")
+	}
+}
+
+func TestRender(t *testing.T) {
+	QuotaError := errors.NewClass("Render Quota Error", errors.UserFacing(),
+		errors.NoCaptureStack(),
+		errors.SetHint("ask an admin to raise the bucket quota"))
+
+	var out bytes.Buffer
+	err := Render(&out, QuotaError.New("bucket photos is full"),
+		RenderOptions{Width: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Render Quota Error: bucket\n" +
+		"  photos is full\n" +
+		"  hint: ask an admin to raise\n" +
+		"    the bucket quota\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	Render(&out, QuotaError.New("full"), RenderOptions{Color: true})
+	if !strings.HasPrefix(out.String(),
+		"\x1b[1m"+Yellow+"Render Quota Error\x1b[0m: full\n") {
+		t.Fatalf("got %q", out.String())
+	}
+
+	out.Reset()
+	Render(&out, errors.New("boom"), RenderOptions{Verbose: true})
+	if !stacksCaptured() {
+		// errors_tiny builds have no stacks to render
+		if out.String() != "Error: boom\n" {
+			t.Fatalf("got %q", out.String())
+		}
+	} else if !strings.Contains(out.String(), "\n  stack:\n    ") {
+		t.Fatalf("got %q", out.String())
+	}
+}
+
+// stacksCaptured is whether this build of the errors package captures
+// stacks, which errors_tiny builds don't.
+func stacksCaptured() bool {
+	return errors.GetStack(errors.New("probe")) != ""
+}
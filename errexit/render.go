@@ -0,0 +1,194 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errexit
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// ANSI color escapes for SetColor.
+const (
+	Red     = "\x1b[31m"
+	Green   = "\x1b[32m"
+	Yellow  = "\x1b[33m"
+	Blue    = "\x1b[34m"
+	Magenta = "\x1b[35m"
+	Cyan    = "\x1b[36m"
+
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+)
+
+var (
+	colorKey = errors.GenSym()
+)
+
+// SetColor returns an ErrorOption (for use in ErrorClass creation or error
+// instantiation) that sets the color Render shows the error's class in, as
+// an ANSI escape such as Yellow. By default, errors.UserFacing errors are
+// yellow (the user can likely fix them), system errors are cyan, and all
+// others are red.
+func SetColor(color string) errors.ErrorOption {
+	return errors.SetData(colorKey, color)
+}
+
+// RenderOptions controls how Render formats an error.
+type RenderOptions struct {
+	// Color enables ANSI colors.
+	Color bool
+	// Verbose includes the error's stack and recorded exits, as for a
+	// --verbose flag.
+	Verbose bool
+	// Width is the column the message and hint are wrapped at. Zero
+	// disables wrapping.
+	Width int
+}
+
+// TerminalOptions returns RenderOptions suited to stderr: colored if stderr
+// is a terminal and the NO_COLOR environment variable is unset, and wrapped
+// at $COLUMNS, or 80 columns.
+func TerminalOptions() RenderOptions {
+	opts := RenderOptions{Width: 80}
+	if fi, err := os.Stderr.Stat(); err == nil &&
+		fi.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == "" {
+		opts.Color = true
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		opts.Width = cols
+	}
+	return opts
+}
+
+// Render writes err to w for a person at a terminal: the class name
+// (colored by family, see SetColor) and the message, then the
+// remediation hint from errors.SetHint, if any, and with opts.Verbose, the
+// stack and exits. This reads much better than the raw dump of a wrapped
+// error's Error method.
+func Render(w io.Writer, err error, opts RenderOptions) error {
+	if err == nil {
+		return nil
+	}
+	name := "Error"
+	if class := errors.GetClass(err); class != nil {
+		name = class.String()
+	}
+	if opts.Color {
+		name = bold + renderColor(err) + name + reset
+	}
+	message := strings.TrimSpace(errors.GetMessage(errors.WrappedErr(err)))
+	var out strings.Builder
+	out.WriteString(wrapText(name+": "+message, opts.Width, "", "  "))
+	if hint := errors.GetHint(err); hint != "" {
+		label := "hint:"
+		if opts.Color {
+			label = Green + label + reset
+		}
+		out.WriteString("\n")
+		out.WriteString(wrapText(label+" "+hint, opts.Width, "  ", "    "))
+	}
+	if opts.Verbose {
+		if errors.HasStack(err) {
+			out.WriteString("\n  stack:\n    ")
+			out.WriteString(strings.Replace(errors.GetStack(err), "\n",
+				"\n    ", -1))
+		}
+		if exits := errors.GetExits(err); exits != "" {
+			out.WriteString("\n  exits:\n    ")
+			out.WriteString(strings.Replace(exits, "\n", "\n    ", -1))
+		}
+	}
+	out.WriteString("\n")
+	_, werr := io.WriteString(w, out.String())
+	return werr
+}
+
+// ExitRendered is like Exit, but prints err with Render and TerminalOptions
+// instead of its raw Error text. verbose is passed on to Render.
+func ExitRendered(err error, verbose bool) {
+	if err == nil {
+		os.Exit(0)
+	}
+	opts := TerminalOptions()
+	opts.Verbose = verbose
+	Render(os.Stderr, err, opts)
+	WriteTrailer(os.Stderr, err)
+	os.Exit(GetExitCode(err, 1))
+}
+
+func renderColor(err error) string {
+	if color, ok := errors.GetData(err, colorKey).(string); ok {
+		return color
+	}
+	switch {
+	case errors.IsUserFacing(err):
+		return Yellow
+	case errors.SystemError.Contains(err):
+		return Cyan
+	}
+	return Red
+}
+
+// wrapText wraps text into lines of at most width columns (unless a single
+// word is longer), starting the first with first and the rest with indent.
+// Color escapes take up no columns. A width of zero disables wrapping.
+func wrapText(text string, width int, first, indent string) string {
+	var out []string
+	current := first
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			out = append(out, current)
+			current = indent
+		}
+		if width <= 0 {
+			current += line
+			continue
+		}
+		empty := true
+		for _, word := range strings.Fields(line) {
+			if !empty && visibleLen(current)+1+visibleLen(word) > width {
+				out = append(out, current)
+				current, empty = indent, true
+			}
+			if !empty {
+				current += " "
+			}
+			current += word
+			empty = false
+		}
+	}
+	return strings.Join(append(out, current), "\n")
+}
+
+// visibleLen counts the runes of s outside ANSI escapes.
+func visibleLen(s string) int {
+	n := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == '\x1b':
+			escape = true
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errgrpc classifies gRPC status errors, so GetClass, Contains and try
+plans work with errors returned by gRPC clients:
+
+	func init() { errgrpc.Register() }
+
+	try.Do(func() { ... }).Catch(errgrpc.UnavailableError, ...)
+
+Errors are recognized by their GRPCStatus() method, so this package doesn't
+depend on google.golang.org/grpc.
+*/
+package errgrpc
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	// GRPCError is the parent of every gRPC status class. Errors with codes
+	// newer than this package are GRPCErrors.
+	GRPCError = errors.SystemError.NewClass("gRPC Error")
+
+	CanceledError           = GRPCError.NewClass("gRPC Canceled Error", status(499))
+	UnknownError            = GRPCError.NewClass("gRPC Unknown Error", status(http.StatusInternalServerError))
+	InvalidArgumentError    = GRPCError.NewClass("gRPC Invalid Argument Error", status(http.StatusBadRequest))
+	DeadlineExceededError   = GRPCError.NewClass("gRPC Deadline Exceeded Error", retryable(http.StatusGatewayTimeout))
+	NotFoundError           = GRPCError.NewClass("gRPC Not Found Error", status(http.StatusNotFound))
+	AlreadyExistsError      = GRPCError.NewClass("gRPC Already Exists Error", status(http.StatusConflict))
+	PermissionDeniedError   = GRPCError.NewClass("gRPC Permission Denied Error", status(http.StatusForbidden))
+	ResourceExhaustedError  = GRPCError.NewClass("gRPC Resource Exhausted Error", retryable(http.StatusTooManyRequests))
+	FailedPreconditionError = GRPCError.NewClass("gRPC Failed Precondition Error", status(http.StatusBadRequest))
+	AbortedError            = GRPCError.NewClass("gRPC Aborted Error", retryable(http.StatusConflict))
+	OutOfRangeError         = GRPCError.NewClass("gRPC Out Of Range Error", status(http.StatusBadRequest))
+	UnimplementedError      = GRPCError.NewClass("gRPC Unimplemented Error", status(http.StatusNotImplemented))
+	InternalError           = GRPCError.NewClass("gRPC Internal Error", status(http.StatusInternalServerError))
+	UnavailableError        = GRPCError.NewClass("gRPC Unavailable Error", retryable(http.StatusServiceUnavailable))
+	DataLossError           = GRPCError.NewClass("gRPC Data Loss Error", status(http.StatusInternalServerError))
+	UnauthenticatedError    = GRPCError.NewClass("gRPC Unauthenticated Error", status(http.StatusUnauthorized))
+
+	// byCode is indexed by gRPC code; code 0 (OK) is not an error.
+	byCode = [...]*errors.ErrorClass{nil, CanceledError, UnknownError,
+		InvalidArgumentError, DeadlineExceededError, NotFoundError,
+		AlreadyExistsError, PermissionDeniedError, ResourceExhaustedError,
+		FailedPreconditionError, AbortedError, OutOfRangeError,
+		UnimplementedError, InternalError, UnavailableError, DataLossError,
+		UnauthenticatedError}
+)
+
+// status maps the class to the HTTP status grpc-gateway uses for its code.
+func status(code int) errors.ErrorOption {
+	return errors.SetPolicy(errors.Policy{HTTPStatus: code})
+}
+
+func retryable(code int) errors.ErrorOption {
+	return errors.SetPolicy(errors.Policy{
+		HTTPStatus: code, Retryable: errors.PolicyYes})
+}
+
+// Register adds Classify to the errors package's classifiers.
+func Register() {
+	errors.RegisterClassifier(Classify)
+}
+
+// Classify returns the class for a gRPC status error (or an error wrapping
+// one), or nil if err isn't one.
+func Classify(err error) *errors.ErrorClass {
+	for ; err != nil; err = unwrap(err) {
+		code, ok := Code(err)
+		if !ok {
+			continue
+		}
+		if code > 0 && code < uint32(len(byCode)) {
+			return byCode[code]
+		}
+		return GRPCError
+	}
+	return nil
+}
+
+// Code returns the gRPC code of err's GRPCStatus(), and whether err has
+// one.
+func Code(err error) (uint32, bool) {
+	method := reflect.ValueOf(err).MethodByName("GRPCStatus")
+	if !method.IsValid() || method.Type().NumIn() != 0 ||
+		method.Type().NumOut() != 1 {
+		return 0, false
+	}
+	st := method.Call(nil)[0]
+	if st.Kind() == reflect.Ptr && st.IsNil() {
+		return 0, false
+	}
+	code := st.MethodByName("Code")
+	if !code.IsValid() || code.Type().NumIn() != 0 ||
+		code.Type().NumOut() != 1 || code.Type().Out(0).Kind() != reflect.Uint32 {
+		return 0, false
+	}
+	return uint32(code.Call(nil)[0].Uint()), true
+}
+
+func unwrap(err error) error {
+	if wrapper, ok := err.(interface{ Unwrap() error }); ok {
+		return wrapper.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errgrpc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// fakeCode and fakeStatus have the shape of grpc's codes.Code and
+// *status.Status.
+type fakeCode uint32
+
+type fakeStatus struct{ code fakeCode }
+
+func (s *fakeStatus) Code() fakeCode { return s.code }
+
+type fakeStatusError struct{ status *fakeStatus }
+
+func (e *fakeStatusError) Error() string { return "rpc error" }
+
+func (e *fakeStatusError) GRPCStatus() *fakeStatus { return e.status }
+
+func TestClassify(t *testing.T) {
+	Register()
+	unavailable := &fakeStatusError{&fakeStatus{14}}
+	if errors.GetClass(unavailable) != UnavailableError {
+		t.Fatalf("classified as %v", errors.GetClass(unavailable))
+	}
+	if !UnavailableError.Contains(fmt.Errorf("calling: %w", unavailable)) ||
+		!errors.Retryable(unavailable) {
+		t.Fatal("wrapped unavailable error not recognized")
+	}
+	if class := Classify(&fakeStatusError{&fakeStatus{99}}); class != GRPCError {
+		t.Fatalf("unknown code classified as %v", class)
+	}
+	if class := Classify(&fakeStatusError{}); class != nil {
+		t.Fatalf("nil status classified as %v", class)
+	}
+	if class := Classify(fmt.Errorf("plain")); class != nil {
+		t.Fatalf("plain error classified as %v", class)
+	}
+}
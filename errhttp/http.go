@@ -22,20 +22,22 @@ hierarchical methods.
 package errhttp
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/spacemonkeygo/errors"
 )
 
 var (
-	statusCode = errors.GenSym()
-	errorBody  = errors.GenSym()
+	errorBody = errors.GenSym()
 )
 
 // SetStatusCode returns an ErrorOption (for use in ErrorClass creation or
-// error instantiation) that controls the error's HTTP status code
+// error instantiation) that controls the error's HTTP status code. It sets
+// the HTTPStatus of the error's errors.Policy.
 func SetStatusCode(code int) errors.ErrorOption {
-	return errors.SetData(statusCode, code)
+	return errors.SetPolicy(errors.Policy{HTTPStatus: code})
 }
 
 // OverrideErrorBody returns an ErrorOption (for use in ErrorClass creation or
@@ -52,24 +54,24 @@ func RestoreDefaultErrorBody() errors.ErrorOption {
 }
 
 // GetStatusCode will return the status code associated with an error, and
-// default_code if none is found.
+// default_code if none is found. The code is set by the nearest class (or
+// the error itself) with SetStatusCode or a Policy with an HTTPStatus.
 func GetStatusCode(err error, default_code int) int {
-	rv := errors.GetData(err, statusCode)
-	sc, ok := rv.(int)
-	if ok {
+	if sc := errors.GetPolicy(err).HTTPStatus; sc != 0 {
 		return sc
 	}
 	return default_code
 }
 
 // GetErrorBody will return the user-visible error message given an error.
-// The message will be determined by errors.GetMessage() unless the error class
-// has an error body overridden by OverrideErrorBody.
+// The message will be determined by errors.PublicMessage() (the message for
+// errors.UserFacing classes, a generic internal error otherwise) unless the
+// error class has an error body overridden by OverrideErrorBody.
 func GetErrorBody(err error) string {
 	rv := errors.GetData(err, errorBody)
 	message, ok := rv.(string)
 	if !ok {
-		return errors.GetMessage(err)
+		return errors.PublicMessage(err)
 	}
 	class := errors.GetClass(err)
 	if class == nil {
@@ -77,3 +79,35 @@ func GetErrorBody(err error) string {
 	}
 	return fmt.Sprintf("%s: %s", class.String(), message)
 }
+
+// Problem is an RFC 7807 problem details object describing an error, with
+// the error's remediation hint (see errors.SetHint) as an extension member.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// GetProblem describes err as a Problem, with the status code from
+// GetStatusCode, the detail from GetErrorBody, and the hint from
+// errors.GetHint.
+func GetProblem(err error, default_code int) Problem {
+	code := GetStatusCode(err, default_code)
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: GetErrorBody(err),
+		Hint:   errors.GetHint(err),
+	}
+}
+
+// WriteProblem writes err to w as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, err error, default_code int) {
+	problem := GetProblem(err, default_code)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
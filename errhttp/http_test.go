@@ -15,8 +15,12 @@
 package errhttp
 
 import (
+	"bytes"
+	"encoding/json"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/spacemonkeygo/errors"
@@ -26,7 +30,7 @@ func writeError(msg string, code int) {}
 
 func Example(t *testing.T) {
 	InvalidRequest := errors.NewClass("Invalid request",
-		SetStatusCode(http.StatusBadRequest))
+		SetStatusCode(http.StatusBadRequest), errors.UserFacing())
 
 	process := func() error {
 		// this method is some sample method somewhere that's doing request
@@ -48,3 +52,165 @@ func Example(t *testing.T) {
 	// http event loop
 	handler(process())
 }
+
+func TestWriteProblem(t *testing.T) {
+	QuotaError := errors.NewClass("Quota Exceeded",
+		SetStatusCode(http.StatusTooManyRequests), errors.UserFacing(),
+		errors.SetHint("increase your quota from the billing console"))
+
+	w := httptest.NewRecorder()
+	WriteProblem(w, QuotaError.New("bucket full"), 500)
+	if w.Code != http.StatusTooManyRequests ||
+		w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("wrote %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{Type: "about:blank", Title: "Too Many Requests",
+		Status: 429, Detail: "Quota Exceeded: bucket full",
+		Hint: "increase your quota from the billing console"}
+	if problem != want {
+		t.Fatalf("got %+v, want %+v", problem, want)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response,
+	error) {
+	return f(req)
+}
+
+func TestTransport(t *testing.T) {
+	FlakyError := errors.NewClass("Flaky Error", errors.SetPolicy(
+		errors.Policy{Retryable: errors.PolicyYes, MaxRetries: 2}))
+
+	failures := 0
+	calls := 0
+	client := &http.Client{Transport: &Transport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response,
+			error) {
+			calls++
+			if calls <= failures {
+				return nil, FlakyError.New("attempt %d", calls)
+			}
+			return &http.Response{StatusCode: 200, Body: http.NoBody,
+				Request: req}, nil
+		}),
+	}}
+
+	failures = 2
+	resp, err := client.Get("http://example.invalid/")
+	if err != nil || resp.StatusCode != 200 || calls != 3 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+
+	calls, failures = 0, 5
+	_, err = client.Get("http://example.invalid/")
+	attempts := GetAttempts(err)
+	if calls != 3 || len(attempts) != 3 {
+		t.Fatalf("made %d calls, recorded %d attempts", calls, len(attempts))
+	}
+	if cast, ok := errors.FindType[*errors.Error](err); !ok ||
+		!cast.Is(FlakyError) {
+		t.Fatalf("got %v", err)
+	}
+
+	calls, failures = 0, 1
+	_, err = client.Post("http://example.invalid/", "text/plain",
+		strings.NewReader("order"))
+	if err == nil || calls != 1 {
+		t.Fatalf("retried a POST: %v after %d calls", err, calls)
+	}
+	calls = 0
+	req, _ := http.NewRequest("POST", "http://example.invalid/",
+		strings.NewReader("order"))
+	req.Header.Set("Idempotency-Key", "order-1")
+	resp, err = client.Do(req)
+	if err != nil || resp.StatusCode != 200 || calls != 2 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	client.Transport.(*Transport).Base = roundTripperFunc(
+		func(*http.Request) (*http.Response, error) {
+			calls++
+			panic("transport bug")
+		})
+	_, err = client.Get("http://example.invalid/")
+	if cast, ok := errors.FindType[*errors.Error](err); !ok ||
+		!cast.Is(errors.PanicError) || calls != 1 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+}
+
+func TestOpenAPIComponents(t *testing.T) {
+	errors.NewClass("Rate Limited", SetStatusCode(http.StatusTooManyRequests),
+		errors.UserFacing(), errors.MessageTemplate("{{.Count}} requests"),
+		errors.SetHint("slow down"))
+	errors.NewClass("Rate Limiter Internal")
+
+	var buf bytes.Buffer
+	if err := WriteOpenAPIComponents(&buf, 400); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Components struct {
+			Schemas   map[string]json.RawMessage
+			Responses map[string]struct {
+				Content map[string]struct {
+					Example Problem
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Components.Schemas["Problem"]; !ok {
+		t.Fatal("missing Problem schema")
+	}
+	if _, ok := doc.Components.Schemas["RateLimiterInternal"]; ok {
+		t.Fatal("internal class exported")
+	}
+	example := doc.Components.Responses["RateLimited"].
+		Content["application/problem+json"].Example
+	want := Problem{Type: "about:blank", Title: "Too Many Requests",
+		Status: 429, Detail: "Rate Limited: {{.Count}} requests",
+		Hint: "slow down"}
+	if example != want {
+		t.Fatalf("got %+v, want %+v", example, want)
+	}
+}
+
+func TestConflictStatusCodes(t *testing.T) {
+	if code := GetStatusCode(errors.ConflictError.New("cas"), 500); code != 409 {
+		t.Fatalf("ConflictError got %d", code)
+	}
+	if code := GetStatusCode(errors.StaleVersionError.New("etag"), 500); code != 412 {
+		t.Fatalf("StaleVersionError got %d", code)
+	}
+}
+
+func TestStatusCodeNearestFirst(t *testing.T) {
+	ServiceError := errors.NewClass("Service Error", SetStatusCode(500))
+	BusyError := ServiceError.NewClass("Busy Error",
+		errors.SetPolicy(errors.Policy{HTTPStatus: 503}))
+	ThrottledError := BusyError.NewClass("Throttled Error",
+		SetStatusCode(429), errors.SetPolicy(errors.Policy{MaxRetries: 3}))
+	if code := GetStatusCode(BusyError.New("busy"), 0); code != 503 {
+		t.Fatalf("BusyError got %d", code)
+	}
+	if code := GetStatusCode(ThrottledError.New("slow"), 0); code != 429 {
+		t.Fatalf("ThrottledError got %d", code)
+	}
+	if code := GetStatusCode(ThrottledError.NewWith("x", SetStatusCode(400)),
+		0); code != 400 {
+		t.Fatalf("error status got %d", code)
+	}
+	if max := errors.GetPolicy(ThrottledError.New("slow")).MaxRetries; max != 3 {
+		t.Fatalf("MaxRetries got %d", max)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+const problemRef = "#/components/schemas/Problem"
+
+// OpenAPIComponents returns OpenAPI 3 components describing the
+// application/problem+json responses WriteProblem sends for the registered
+// errors.UserFacing classes: a Problem schema, and for each class (named by
+// its ClassDescriptor Code) a schema pinning its status and title, and a
+// response with an example built from its MessageTemplate (or MessageFormat)
+// and hint. Classes without a status code get default_code, as with
+// GetStatusCode. Generating the API documentation from the taxonomy keeps it
+// in step with the responses actually sent.
+func OpenAPIComponents(default_code int) map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Problem": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"type", "title", "status"},
+			"properties": map[string]interface{}{
+				"type":   map[string]interface{}{"type": "string"},
+				"title":  map[string]interface{}{"type": "string"},
+				"status": map[string]interface{}{"type": "integer"},
+				"detail": map[string]interface{}{"type": "string"},
+				"hint":   map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	responses := make(map[string]interface{})
+	for _, desc := range errors.DescribeClasses() {
+		if !desc.UserFacing {
+			continue
+		}
+		class := errors.LookupClass(desc.Path)
+		if class == nil {
+			continue
+		}
+		code := default_code
+		if desc.HTTPStatus != 0 {
+			code = desc.HTTPStatus
+		}
+		schemas[desc.Code] = map[string]interface{}{
+			"description":   desc.Path,
+			"x-error-class": desc.Path,
+			"x-error-id":    desc.ID,
+			"allOf": []interface{}{
+				map[string]interface{}{"$ref": problemRef},
+				map[string]interface{}{
+					"properties": map[string]interface{}{
+						"status": map[string]interface{}{
+							"type": "integer", "enum": []int{code}},
+						"title": map[string]interface{}{
+							"type": "string",
+							"enum": []string{http.StatusText(code)}},
+					},
+				},
+			},
+		}
+		example := Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(code),
+			Status: code,
+			Hint:   desc.Hint,
+		}
+		if desc.Template != "" {
+			example.Detail = class.String() + ": " + desc.Template
+		} else if desc.Format != "" {
+			example.Detail = class.String() + ": " + desc.Format
+		}
+		responses[desc.Code] = map[string]interface{}{
+			"description": desc.Path,
+			"content": map[string]interface{}{
+				"application/problem+json": map[string]interface{}{
+					"schema": map[string]interface{}{
+						"$ref": "#/components/schemas/" + desc.Code},
+					"example": example,
+				},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"schemas":   schemas,
+		"responses": responses,
+	}
+}
+
+// WriteOpenAPIComponents writes OpenAPIComponents to w as a JSON document
+// with a single "components" member, ready to merge into an API spec.
+func WriteOpenAPIComponents(w io.Writer, default_code int) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"components": OpenAPIComponents(default_code),
+	})
+}
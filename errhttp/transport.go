@@ -0,0 +1,147 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errhttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var attemptsKey = errors.GenSym()
+
+// Transport is an http.RoundTripper that retries failed idempotent requests
+// according to the errors.Policy of the failure's class. Each transport
+// failure is classified with errors.GetClass, and the request is retried
+// while the class is errors.Retryable, up to the class's MaxRetries (or the
+// Transport's, if the class doesn't set one). A panic in the underlying
+// RoundTripper becomes an errors.PanicError, which is only retried if its
+// policy says so.
+//
+// When the request finally fails, RoundTrip returns the last failure wrapped
+// in its class, with the failure of every attempt available from
+// GetAttempts. http.Client wraps the error in a *url.Error, so use
+// errors.FindType to get at it.
+type Transport struct {
+	// Base makes the requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// MaxRetries limits retries for classes whose policy doesn't set
+	// MaxRetries.
+	MaxRetries int
+	// Backoff, if set, returns how long to wait before the given retry,
+	// counting from 1.
+	Backoff func(retry int) time.Duration
+}
+
+// RoundTrip implements http.RoundTripper. Only idempotent requests are
+// retried, since a failed attempt may still have reached the server: those
+// with a GET, HEAD, OPTIONS, TRACE, PUT or DELETE method, and others carrying
+// an Idempotency-Key (or X-Idempotency-Key) header. Requests with a body are
+// only retried if the body can be recreated with GetBody.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var causes []error
+	for retry := 0; ; retry++ {
+		attempt := req
+		if retry > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				causes = append(causes, err)
+				break
+			}
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+		resp, err := t.attempt(attempt)
+		if err == nil {
+			return resp, nil
+		}
+		causes = append(causes, err)
+		if !t.retry(req, err, retry+1) {
+			break
+		}
+	}
+	last := causes[len(causes)-1]
+	class := errors.GetClass(last)
+	if class == nil {
+		class = errors.SystemError
+	}
+	return nil, class.Wrap(last, errors.SetData(attemptsKey, causes))
+}
+
+// attempt makes one request, turning a panic into an error.
+func (t *Transport) attempt(req *http.Request) (resp *http.Response,
+	err error) {
+	defer errors.CatchPanic(&err)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// retry reports whether the request should be retried after err, waiting
+// out the backoff first.
+func (t *Transport) retry(req *http.Request, err error, retry int) bool {
+	if !errors.Retryable(err) || !idempotent(req) {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	max := errors.GetPolicy(err).MaxRetries
+	if max == 0 {
+		max = t.MaxRetries
+	}
+	if retry > max || req.Context().Err() != nil {
+		return false
+	}
+	var wait time.Duration
+	if t.Backoff != nil {
+		wait = t.Backoff(retry)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+// idempotent reports whether req can safely be sent more than once.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xkey := req.Header["X-Idempotency-Key"]
+	return key || xkey
+}
+
+// GetAttempts returns the failure of each attempt a Transport made, in
+// order, for an error returned by a Transport (or a *url.Error wrapping
+// one). It returns nil for other errors.
+func GetAttempts(err error) []error {
+	cast, ok := errors.FindType[*errors.Error](err)
+	if !ok {
+		return nil
+	}
+	attempts, _ := cast.GetData(attemptsKey).([]error)
+	return attempts
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errlint
+
+import (
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer runs Check as a go/analysis pass, for the errlint command and
+// other drivers such as `go vet -vettool`.
+var Analyzer = &analysis.Analyzer{
+	Name: "errlint",
+	Doc:  "check for pitfalls of the spacemonkeygo errors and try packages",
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	for _, d := range Check(pass.Files) {
+		pass.Report(analysis.Diagnostic{Pos: d.Pos, Category: d.Category,
+			Message: d.Message})
+	}
+	return nil, nil
+}
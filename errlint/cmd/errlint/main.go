@@ -0,0 +1,30 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command errlint reports the pitfalls errlint.Check finds in the packages
+// named on its command line. Run it standalone, or under go vet:
+//
+//	go install github.com/spacemonkeygo/errors/errlint/cmd/errlint@latest
+//	go vet -vettool=$(which errlint) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/spacemonkeygo/errors/errlint"
+)
+
+func main() {
+	singlechecker.Main(errlint.Analyzer)
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errlint mechanically checks code for the documented pitfalls of the
+errors and try packages:
+
+  - error classes created inside functions (other than init), which makes a
+    new class, and a new registry entry, on every call
+  - Catch handlers chained after a CatchAll, which can never run
+  - try plans whose Done() call was forgotten, which never run at all
+  - catch handlers that silently swallow the error: they neither use it nor
+    call anything (to log, rethrow or translate it)
+
+The checks are purely syntactic, so Check depends only on the standard
+library, and takes the same parsed files a go/analysis Pass carries.
+Analyzer wraps it for go/analysis drivers, and the errlint command
+(errlint/cmd/errlint) runs it standalone or with `go vet -vettool`.  Those
+need golang.org/x/tools, so errlint is its own module, keeping the errors
+and try packages free of dependencies.
+*/
+package errlint
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+const (
+	errorsPath = "github.com/spacemonkeygo/errors"
+	tryPath    = "github.com/spacemonkeygo/errors/try"
+)
+
+// Categories of Diagnostic.
+const (
+	ClassInFunction    = "class-in-function"
+	CatchAfterCatchAll = "catch-after-catchall"
+	MissingDone        = "missing-done"
+	SwallowedError     = "swallowed-error"
+)
+
+// Diagnostic is a problem found by Check.
+type Diagnostic struct {
+	Pos      token.Pos
+	Category string
+	Message  string
+}
+
+var (
+	catches = map[string]bool{
+		"Catch": true, "CatchErr": true, "CatchLayer": true,
+		"CatchEach": true, "CatchCtx": true,
+	}
+	catchAlls = map[string]bool{
+		"CatchAll": true, "CatchAllErr": true, "CatchAllCtx": true,
+	}
+	classConstructors = map[string]bool{
+		"NewClass": true, "NewTypedClass": true, "Define": true,
+	}
+)
+
+// Check returns the problems in files, in the order found.
+func Check(files []*ast.File) []Diagnostic {
+	var diags []Diagnostic
+	for _, file := range files {
+		c := checker{
+			errorsName: importName(file, errorsPath, "errors"),
+			tryName:    importName(file, tryPath, "try"),
+		}
+		if c.errorsName == "" && c.tryName == "" {
+			continue
+		}
+		c.file(file)
+		diags = append(diags, c.diags...)
+	}
+	return diags
+}
+
+type checker struct {
+	errorsName string
+	tryName    string
+	diags      []Diagnostic
+}
+
+func (c *checker) report(pos token.Pos, category, message string) {
+	c.diags = append(c.diags, Diagnostic{
+		Pos: pos, Category: category, Message: message})
+}
+
+func (c *checker) file(file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		inInit := fn.Recv == nil && fn.Name.Name == "init"
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ExprStmt:
+				c.exprStmt(n)
+			case *ast.CallExpr:
+				if !inInit {
+					c.classCreation(n)
+				}
+				c.catch(n)
+			}
+			return true
+		})
+	}
+}
+
+// classCreation flags errors.NewClass, errors.Define, errors.NewTypedClass,
+// and parent.NewClass("name", ...). A NewClass method whose first argument
+// isn't a string literal, like (*errors.Plugin).NewClass(parent, name), is
+// left alone.
+func (c *checker) classCreation(call *ast.CallExpr) {
+	fun := call.Fun
+	if index, ok := fun.(*ast.IndexExpr); ok {
+		fun = index.X
+	}
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok || !classConstructors[sel.Sel.Name] {
+		return
+	}
+	if !c.isPkg(sel.X, c.errorsName) {
+		if sel.Sel.Name != "NewClass" || len(call.Args) == 0 {
+			return
+		}
+		if lit, ok := call.Args[0].(*ast.BasicLit); !ok ||
+			lit.Kind != token.STRING {
+			return
+		}
+	}
+	c.report(call.Pos(), ClassInFunction, "error class created inside a "+
+		"function; declare it in a package-level var so it's created once")
+}
+
+// catch flags typed catches after a CatchAll, and handlers that swallow.
+func (c *checker) catch(call *ast.CallExpr) {
+	name, recv, ok := method(call)
+	if !ok || (!catches[name] && !catchAlls[name]) || !c.isPlan(recv) {
+		return
+	}
+	if catches[name] {
+		for inner := recv; ; {
+			innerName, innerRecv, ok := method(inner)
+			if !ok {
+				break
+			}
+			if catchAlls[innerName] {
+				pos := call.Fun.(*ast.SelectorExpr).Sel.Pos()
+				c.report(pos, CatchAfterCatchAll, name+" after "+innerName+
+					" can never run; move it before")
+				break
+			}
+			inner = innerRecv
+		}
+	}
+	if len(call.Args) == 0 {
+		return
+	}
+	handler, ok := call.Args[len(call.Args)-1].(*ast.FuncLit)
+	if ok && swallows(handler) {
+		c.report(handler.Pos(), SwallowedError, name+" handler swallows "+
+			"the error silently; log, rethrow or translate it")
+	}
+}
+
+// exprStmt flags plans used as statements without a Done().
+func (c *checker) exprStmt(stmt *ast.ExprStmt) {
+	call, ok := stmt.X.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	name, _, ok := method(call)
+	if ok && name == "Done" {
+		return
+	}
+	if c.isPlan(call) {
+		c.report(call.Pos(), MissingDone, "try plan is never run; "+
+			"finish it with Done()")
+	}
+}
+
+// isPlan reports whether expr is a method chain starting at try.Do,
+// try.DoNamed or try.DoCtx.
+func (c *checker) isPlan(expr ast.Expr) bool {
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		name, recv, ok := method(call)
+		if !ok {
+			return false
+		}
+		if c.isPkg(recv, c.tryName) {
+			return name == "Do" || name == "DoNamed" || name == "DoCtx"
+		}
+		expr = recv
+	}
+}
+
+func (c *checker) isPkg(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && name != "" && ident.Name == name && ident.Obj == nil
+}
+
+// method splits a call like recv.name(...).
+func method(expr ast.Expr) (name string, recv ast.Expr, ok bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, false
+	}
+	return sel.Sel.Name, sel.X, true
+}
+
+// swallows reports whether a handler neither uses its error nor calls
+// anything.
+func swallows(handler *ast.FuncLit) bool {
+	params := handler.Type.Params.List
+	if len(params) == 0 {
+		return false
+	}
+	names := params[len(params)-1].Names
+	var errName *ast.Object
+	if len(names) > 0 && names[len(names)-1].Name != "_" {
+		errName = names[len(names)-1].Obj
+	}
+	quiet := true
+	ast.Inspect(handler.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr, *ast.ReturnStmt, *ast.GoStmt, *ast.DeferStmt:
+			quiet = false
+		case *ast.Ident:
+			if errName != nil && n.Obj == errName {
+				quiet = false
+			}
+		}
+		return quiet
+	})
+	return quiet
+}
+
+// importName returns the name path is imported under in file, or "" if it
+// isn't imported (or is imported with _ or .).
+func importName(file *ast.File, path, def string) string {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != path {
+			continue
+		}
+		if spec.Name == nil {
+			return def
+		}
+		if spec.Name.Name == "_" || spec.Name.Name == "." {
+			return ""
+		}
+		return spec.Name.Name
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errlint
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const source = `package sample
+
+import (
+	"log"
+
+	errs "github.com/spacemonkeygo/errors"
+	"github.com/spacemonkeygo/errors/try"
+)
+
+var FruitError = errs.NewClass("Fruit Error")
+
+func init() {
+	_ = errs.NewClass("Init Error")
+}
+
+func handle(plugin *errs.Plugin) {
+	_ = errs.NewClass("Local Error")                  // class-in-function
+	_ = FruitError.NewClass("Local Apple Error")      // class-in-function
+	_ = plugin.NewClass(FruitError, "Plugin Error")
+
+	try.Do(func() {}).CatchAll(func(e error) {     // swallowed-error
+	}).Catch(FruitError, func(e *errs.Error) {     // catch-after-catchall
+		log.Print(e)
+	}).Done()
+
+	try.Do(func() {}).Catch(FruitError, func(e *errs.Error) { // missing-done
+		panic(e)
+	})
+
+	var caught error
+	try.Do(func() {}).CatchAll(func(e error) { caught = e }).Done()
+	_ = caught
+
+	handled := false
+	try.Do(func() {}).Catch(FruitError, func(e *errs.Error) { // swallowed-error
+		handled = true
+	}).Done()
+	_ = handled
+}
+`
+
+func TestCheck(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sample.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every line with a trailing category comment should be reported once
+	want := map[int]string{}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if text := comment.Text[2:]; text[0] == ' ' {
+				want[fset.Position(comment.Pos()).Line] = text[1:]
+			}
+		}
+	}
+	got := map[int]string{}
+	for _, d := range Check([]*ast.File{file}) {
+		line := fset.Position(d.Pos).Line
+		if _, ok := got[line]; ok {
+			t.Errorf("line %d reported twice", line)
+		}
+		got[line] = d.Category
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	if err := analysis.Validate([]*analysis.Analyzer{Analyzer}); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sample.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Analyzer: Analyzer,
+		Fset:     fset,
+		Files:    []*ast.File{file},
+		Report:   func(d analysis.Diagnostic) { reported = append(reported, d) },
+	}
+	if _, err := Analyzer.Run(pass); err != nil {
+		t.Fatal(err)
+	}
+	checked := Check([]*ast.File{file})
+	if len(reported) != len(checked) || len(reported) == 0 {
+		t.Fatalf("reported %d diagnostics, Check found %d",
+			len(reported), len(checked))
+	}
+	for i, d := range checked {
+		if reported[i].Pos != d.Pos || reported[i].Category != d.Category {
+			t.Errorf("diagnostic %d: got %+v, want %+v", i, reported[i], d)
+		}
+	}
+}
@@ -17,46 +17,69 @@ package errors
 import (
 	"errors"
 	"fmt"
-	"io"
-	"net"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
 	logOnCreation      = GenSym()
 	captureStack       = GenSym()
 	disableInheritance = GenSym()
+	messagePrefix      = GenSym()
 )
 
 // ErrorClass is the basic hierarchical error type. An ErrorClass generates
 // actual errors, but the error class controls properties of the errors it
 // generates, such as where those errors are in the hierarchy, whether or not
 // they capture the stack on instantiation, and so forth.
+//
+// A nil *ErrorClass is usable, so libraries can expose optional class
+// variables that users may leave unset: it makes errors (and subclasses) as
+// HierarchicalError does, and contains and is nothing.
 type ErrorClass struct {
+	// counters are first so they are 64-bit aligned for sync/atomic.
+	created int64
+	caught  int64
+
 	parent *ErrorClass
 	name   string
 	data   map[DataKey]interface{}
+	// isolated is set if the class was created with DisableInheritance, so
+	// settings resolved by walking the parents stop at it.
+	isolated bool
+	// canon is the class this one is interned to (see Namespace) or, once
+	// its plugin is unloaded, its tombstone under UnloadedPluginError. It's
+	// set after the class may be in use, so it's atomic.
+	canon atomic.Pointer[ErrorClass]
+	// bit and ancestry are the class' bit and the set of its ancestors' bits
+	// (its own included), for IsAny.
+	bit      int
+	ancestry []uint64
 }
 
 var (
 	// HierarchicalError is the base class for all hierarchical errors generated
 	// through this class.
 	HierarchicalError = &ErrorClass{
-		parent: nil,
-		name:   "Error",
-		data:   map[DataKey]interface{}{captureStack: true}}
+		parent:   nil,
+		name:     "Error",
+		data:     map[DataKey]interface{}{captureStack: true},
+		bit:      0,
+		ancestry: []uint64{1 << 0}}
 
 	// SystemError is the base error class for errors not generated through this
 	// errors library. It is not expected that anyone would ever generate new
 	// errors from a SystemError type or make subclasses.
 	SystemError = &ErrorClass{
-		parent: nil,
-		name:   "System Error",
-		data:   map[DataKey]interface{}{}}
+		parent:   nil,
+		name:     "System Error",
+		data:     map[DataKey]interface{}{},
+		bit:      1,
+		ancestry: []uint64{1 << 1}}
 )
 
 // An ErrorOption is something that controls behavior of specific error
@@ -97,6 +120,20 @@ func NoCaptureStack() ErrorOption {
 	return SetData(captureStack, false)
 }
 
+// SetMessagePrefix controls how the error class name is rendered in front of
+// messages from the error, class, and its descendents. In template, {class}
+// is replaced with the class name and {path} with the class Path. The default
+// is "{class}: ".
+func SetMessagePrefix(template string) ErrorOption {
+	return SetData(messagePrefix, template)
+}
+
+// OmitClassName renders messages from the error, class, and its descendents
+// without any class name prefix. It is the same as SetMessagePrefix("").
+func OmitClassName() ErrorOption {
+	return SetMessagePrefix("")
+}
+
 // If DisableInheritance is provided, the error or error class will belong to
 // its ancestors, but will not inherit their settings and options. Use with
 // caution, and may disappear in future releases.
@@ -127,9 +164,11 @@ func New(text string) error {
 }
 
 // NewClass creates an error class with the provided name and options. The new
-// class will descend from the receiver.
+// class will descend from the receiver, or HierarchicalError if the receiver
+// is nil.
 func (parent *ErrorClass) NewClass(name string,
 	options ...ErrorOption) *ErrorClass {
+	parent = parent.orRoot()
 
 	ec := &ErrorClass{
 		parent: parent,
@@ -148,11 +187,13 @@ func (parent *ErrorClass) NewClass(name string,
 				ec.data[key] = val
 			}
 		}
-		return ec
 	} else {
 		delete(ec.data, disableInheritance)
+		ec.isolated = true
 	}
 
+	register(ec)
+	ec.setAncestry()
 	return ec
 }
 
@@ -170,11 +211,25 @@ func (e *ErrorClass) MustAddData(key DataKey, value interface{}) {
 // GetData will return any data set on the error class for the given key. It
 // returns nil if there is no data set for that key.
 func (e *ErrorClass) GetData(key DataKey) interface{} {
+	if e == nil {
+		return nil
+	}
 	return e.data[key]
 }
 
+// orRoot returns the receiver, or HierarchicalError if it's nil.
+func (e *ErrorClass) orRoot() *ErrorClass {
+	if e == nil {
+		return HierarchicalError
+	}
+	return e
+}
+
 // Parent returns this error class' direct ancestor.
 func (e *ErrorClass) Parent() *ErrorClass {
+	if e == nil {
+		return nil
+	}
 	return e.parent
 }
 
@@ -187,31 +242,72 @@ func (e *ErrorClass) String() string {
 }
 
 // Is returns true if the receiver class is or is a descendent of parent.
+// Namespaced classes with the same path are considered the same class.
 func (e *ErrorClass) Is(parent *ErrorClass) bool {
+	parent = parent.canonical()
 	for check := e; check != nil; check = check.parent {
-		if check == parent {
+		if check.canonical() == parent {
 			return true
 		}
 	}
+	// tombstones descend from UnloadedPluginError as well.
+	if canon := e.canonical(); canon != e && canon.parent != nil {
+		return canon.parent.Is(parent)
+	}
 	return false
 }
 
-// frame logs the pc at some point during execution.
+// CommonClass returns the most specific class that the classes of all the
+// non-nil errs (as GetClass reports them) are or descend from, such as for
+// labeling an aggregate of parallel failures with one representative class.
+// It returns nil if there are no non-nil errs, or the classes share no
+// ancestor (as with a HierarchicalError and a SystemError).
+func CommonClass(errs ...error) *ErrorClass {
+	var common *ErrorClass
+	first := true
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		class := GetClass(err)
+		if first {
+			common, first = class, false
+		}
+		for common != nil && !class.Is(common) {
+			common = common.parent
+		}
+		if common == nil {
+			return nil
+		}
+	}
+	return common
+}
+
+// frame logs the pc at some point during execution, and optionally a label
+// describing why it was recorded.
 type frame struct {
-	pc uintptr
+	pc    uintptr
+	label string
 }
 
 // String returns a human readable form of the frame.
 func (e frame) String() string {
-	if e.pc == 0 {
-		return "unknown.unknown:0"
+	loc := "unknown.unknown:0"
+	if f := runtime.FuncForPC(e.pc); e.pc != 0 && f != nil {
+		file, line := f.FileLine(e.pc)
+		loc = fmt.Sprintf("%s:%s:%d", f.Name(), filepath.Base(file), line)
 	}
-	f := runtime.FuncForPC(e.pc)
-	if f == nil {
-		return "unknown.unknown:0"
+	if e.label != "" {
+		return fmt.Sprintf("%s (%s)", loc, e.label)
 	}
-	file, line := f.FileLine(e.pc)
-	return fmt.Sprintf("%s:%s:%d", f.Name(), filepath.Base(file), line)
+	return loc
+}
+
+// exit is a frame recorded as an error passes through, with the time it was
+// recorded.
+type exit struct {
+	frame
+	at time.Time
 }
 
 // callerState records the pc into an frame for two callers up.
@@ -225,7 +321,7 @@ func callerState(depth int) frame {
 
 // record will record the pc at the given depth into the error if it is
 // capable of recording it.
-func record(err error, depth int) error {
+func record(err error, depth int, label string) error {
 	if err == nil {
 		return nil
 	}
@@ -233,14 +329,20 @@ func record(err error, depth int) error {
 	if !ok {
 		return err
 	}
-	cast.exits = append(cast.exits, callerState(depth))
+	// exits are a trail of where the error went, not part of what it
+	// reports, so recording them doesn't count as modifying an exported
+	// error.
+	cast.checkLive()
+	f := callerState(depth)
+	f.label = label
+	cast.exits = append(cast.exits, exit{frame: f, at: time.Now()})
 	return cast
 }
 
 // Record will record the current pc on the given error if possible, adding
 // to the error's recorded exits list. Returns the given error argument.
 func Record(err error) error {
-	return record(err, 3)
+	return record(err, 3, "")
 }
 
 // RecordBefore will record the pc depth frames above the current stack frame
@@ -248,7 +350,14 @@ func Record(err error) error {
 // Record(err) is equivalent to RecordBefore(err, 0). Returns the given error
 // argument.
 func RecordBefore(err error, depth int) error {
-	return record(err, 3+depth)
+	return record(err, 3+depth, "")
+}
+
+// RecordBeforeLabeled is like RecordBefore, but the recorded exit will be
+// shown with the given label, such as the name of the operation the error is
+// leaving. Returns the given error argument.
+func RecordBeforeLabeled(err error, depth int, label string) error {
+	return record(err, 3+depth, label)
 }
 
 // Error is the type that represents a specific error instance. It is not
@@ -256,16 +365,43 @@ func RecordBefore(err error, depth int) error {
 // should use the 'error' interface and errors package methods that operate
 // on errors instances.
 type Error struct {
-	err   error
-	class *ErrorClass
-	stack []frame
-	exits []frame
-	data  map[DataKey]interface{}
+	// id is first so it is 64-bit aligned for sync/atomic.
+	id uint64
+
+	err     error
+	class   *ErrorClass
+	stack   []frame
+	exits   []exit
+	created time.Time
+	// fakeStack replaces stack for errors made by ForTest, and for errors
+	// rehydrated by UnmarshalJSON, whose stacks are from another process.
+	fakeStack []string
+	// stackDeferred is set while stack holds just the creation frame of an
+	// error whose stack capture is deferred until it escapes, and
+	// escapeOnce captures it.
+	stackDeferred bool
+	escapeOnce    sync.Once
+	// stackText memoizes the rendered stack, since resolving frames is slow.
+	stackOnce sync.Once
+	stackText string
+	data      map[DataKey]interface{}
+	origin    uintptr
+	// fatalReported is set once the error has been passed to ReportFatal.
+	fatalReported uint32
+	// exported is set once the error has been passed to Export.
+	exported uint32
+	pooled   bool
+	released bool
+	// travels holds the error's serialized and deserialized journey events,
+	// under travelMu, since marshaling an error doesn't otherwise modify it.
+	travelMu sync.Mutex
+	travels  []JourneyEvent
 }
 
 // GetData returns the value associated with the given DataKey on this error
 // or any of its ancestors. Please see the example for SetData
 func (e *Error) GetData(key DataKey) interface{} {
+	e.checkLive()
 	if e.data != nil {
 		val, ok := e.data[key]
 		if ok {
@@ -288,11 +424,58 @@ func GetData(err error, key DataKey) interface{} {
 	return nil
 }
 
+// AttachData sets data on an existing error, for code that learns something
+// worth recording about an error after it was created (as try plans do with
+// captured logs). It returns false if err isn't an *Error. Errors aren't
+// threadsafe to modify, so only attach data to errors no other goroutine is
+// using yet.
+func AttachData(err error, key DataKey, value interface{}) bool {
+	cast, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	cast.checkMutable()
+	cast.setData(key, value)
+	return true
+}
+
+// Annotate is AttachData for bookkeeping about how an error was handled,
+// such as its duration, the handlers that caught it, or the logs captured
+// around it, rather than about what went wrong. Annotations may be added
+// after the error is exported (see Export) without tripping
+// Config.Debugexport; they aren't in the exported view.
+func Annotate(err error, key DataKey, value interface{}) bool {
+	cast, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	cast.checkLive()
+	cast.setData(key, value)
+	return true
+}
+
+func (e *Error) setData(key DataKey, value interface{}) {
+	if e.data == nil {
+		e.data = make(map[DataKey]interface{})
+	}
+	e.data[key] = value
+}
+
 func (e *ErrorClass) wrap(err error, classes []*ErrorClass,
+	options []ErrorOption) error {
+	return e.wrapFrom(2, err, classes, options)
+}
+
+// wrapFrom is wrap, callers frames up from the caller's code: the exported
+// entry point and everything between it and wrapFrom. Stacks and
+// Config.Adaptivestacks creation sites start at the caller's code, however
+// deep the entry point is.
+func (e *ErrorClass) wrapFrom(callers int, err error, classes []*ErrorClass,
 	options []ErrorOption) error {
 	if err == nil {
 		return nil
 	}
+	e = e.orRoot()
 	if ec, ok := err.(*Error); ok {
 		if ec.Is(e) {
 			if len(options) == 0 {
@@ -309,25 +492,51 @@ func (e *ErrorClass) wrap(err error, classes []*ErrorClass,
 		}
 	}
 
-	rv := &Error{err: err, class: e}
+	rv := newError(err, e)
+	rv.created = time.Now()
+	atomic.AddInt64(&e.created, 1)
 	if len(options) > 0 {
-		rv.data = make(map[DataKey]interface{})
+		if rv.data == nil {
+			rv.data = make(map[DataKey]interface{})
+		}
 		for _, option := range options {
 			option(rv.data)
 		}
+		sampleData(rv.data)
 	}
+	if _, ok := err.(*Error); !ok {
+		rv.data = decompose(err, rv.data)
+	}
+	rv.err = scrub(rv, err)
+	degraded := rv.degrade()
 
-	if boolWrapper(rv.GetData(captureStack), false) {
-		var pcs [256]uintptr
-		amount := runtime.Callers(3, pcs[:])
-		rv.stack = make([]frame, amount)
-		for i := 0; i < amount; i++ {
-			rv.stack[i] = frame{pcs[i]}
+	if stackSupport && !degraded &&
+		boolWrapper(rv.GetData(captureStack), false) &&
+		novelSite(e, callers+3) {
+		if boolWrapper(rv.GetData(deferStack), false) {
+			rv.recordCreation(callers + 3)
+		} else {
+			var buf [256]uintptr
+			pcs := stackBuffer(&buf)
+			amount := runtime.Callers(callers+2, pcs)
+			if cap(rv.stack) < amount {
+				rv.stack = make([]frame, amount)
+			} else {
+				rv.stack = rv.stack[:amount]
+			}
+			for i := 0; i < amount; i++ {
+				rv.stack[i] = frame{pc: pcs[i]}
+			}
 		}
 	}
+	if boolWrapper(rv.GetData(recordGoroutine), false) {
+		rv.setGoroutine()
+	}
+	rv.accountMemory()
 	if boolWrapper(rv.GetData(logOnCreation), false) {
 		LogWithStack(rv.Error())
 	}
+	journal(rv)
 	return rv
 }
 
@@ -356,13 +565,8 @@ func (e *ErrorClass) NewWith(message string, options ...ErrorOption) error {
 // Error conforms to the error interface. Error will return the backtrace if
 // it was captured and any recorded exits.
 func (e *Error) Error() string {
-	message := strings.TrimRight(e.err.Error(), "\n ")
-	if strings.Contains(message, "\n") {
-		message = fmt.Sprintf("%s:\n  %s", e.class.String(),
-			strings.Replace(message, "\n", "\n  ", -1))
-	} else {
-		message = fmt.Sprintf("%s: %s", e.class.String(), message)
-	}
+	e.checkLive()
+	message := e.decorate(strings.TrimRight(e.err.Error(), "\n "))
 	if stack := e.Stack(); stack != "" {
 		message = fmt.Sprintf(
 			"%s\n\"%s\" backtrace:\n%s", message, e.class, stack)
@@ -376,21 +580,55 @@ func (e *Error) Error() string {
 
 // Message returns just the error message without the backtrace or exits.
 func (e *Error) Message() string {
-	message := strings.TrimRight(GetMessage(e.err), "\n ")
-	if strings.Contains(message, "\n") {
-		return fmt.Sprintf("%s:\n  %s", e.class.String(),
-			strings.Replace(message, "\n", "\n  ", -1))
+	e.checkLive()
+	return e.decorate(strings.TrimRight(GetMessage(e.err), "\n "))
+}
+
+// decorate prefixes the given message with the error's class name, or as
+// configured with SetMessagePrefix.
+func (e *Error) decorate(message string) string {
+	return e.decorateRepeated(message, 1)
+}
+
+// decorateRepeated is like decorate, but notes that the class was repeated
+// count times.
+func (e *Error) decorateRepeated(message string, count int) string {
+	name := e.class.String()
+	if count > 1 {
+		name = fmt.Sprintf("%s (x%d)", name, count)
+	}
+	prefix := name + ": "
+	if tmpl, ok := e.GetData(messagePrefix).(string); ok {
+		prefix = strings.NewReplacer(
+			"{class}", name,
+			"{path}", e.class.Path()).Replace(tmpl)
 	}
-	return fmt.Sprintf("%s: %s", e.class.String(), message)
+	if !strings.Contains(message, "\n") {
+		return prefix + message
+	}
+	message = strings.Replace(message, "\n", "\n  ", -1)
+	if prefix == "" {
+		return message
+	}
+	return fmt.Sprintf("%s\n  %s", strings.TrimRight(prefix, " "), message)
 }
 
 // WrappedErr returns the wrapped error, if the current error is simply
 // wrapping some previously returned error or system error. You probably want
 // the package-level WrappedErr
 func (e *Error) WrappedErr() error {
+	e.checkLive()
 	return e.err
 }
 
+// Unwrap returns the wrapped error, as WrappedErr does, so that the standard
+// library's errors.Is and errors.As see through the error: for example,
+// errors.Is(IOError.Wrap(io.EOF), io.EOF) is true. (*Error).Is keeps its
+// class-matching signature, so it isn't consulted by errors.Is.
+func (e *Error) Unwrap() error {
+	return e.WrappedErr()
+}
+
 // WrappedErr returns the wrapped error, if the current error is simply
 // wrapping some previously returned error or system error. If the error isn't
 // hierarchical it is just returned.
@@ -405,6 +643,7 @@ func WrappedErr(err error) error {
 // Class will return the appropriate error class for the given error. You
 // probably want the package-level GetClass.
 func (e *Error) Class() *ErrorClass {
+	e.checkLive()
 	return e.class
 }
 
@@ -425,14 +664,20 @@ func GetClass(err error) *ErrorClass {
 // Stack will return the stack associated with the error if one is found. You
 // probably want the package-level GetStack.
 func (e *Error) Stack() string {
-	if len(e.stack) > 0 {
-		frames := make([]string, len(e.stack))
-		for i, f := range e.stack {
-			frames[i] = f.String()
+	if e.fakeStack != nil {
+		return strings.Join(e.fakeStack, "\n")
+	}
+	e.escape()
+	e.stackOnce.Do(func() {
+		if len(e.stack) > 0 {
+			frames := make([]string, len(e.stack))
+			for i, f := range e.stack {
+				frames[i] = f.String()
+			}
+			e.stackText = strings.Join(frames, "\n")
 		}
-		return strings.Join(frames, "\n")
-	}
-	return ""
+	})
+	return e.stackText
 }
 
 // GetStack will return the stack associated with the error if one is found.
@@ -447,6 +692,62 @@ func GetStack(err error) string {
 	return cast.Stack()
 }
 
+// StackText returns the raw text of the stack associated with the error, as
+// Stack does. The stack is captured as program counters and only resolved to
+// text on the first call; the text is cached for later calls, so code that
+// writes it to logs repeatedly doesn't pay for symbolization each time.
+func (e *Error) StackText() string {
+	return e.Stack()
+}
+
+// HasStack reports whether a stack was captured for the error, without
+// resolving it to text. Renderers can use it to skip the backtrace section
+// cheaply.
+func (e *Error) HasStack() bool {
+	return e.fakeStack != nil || len(e.stack) > 0 || e.stackText != ""
+}
+
+// StackFrames returns the stack associated with the error as frames,
+// innermost first, for code that filters or serializes stacks rather than
+// printing them. The stack is captured as program counters, so the frames
+// are complete (inlined calls included) and resolved only on demand. Errors
+// made by ForTest have no program counters, so they have no frames.
+func (e *Error) StackFrames() []runtime.Frame {
+	e.escape()
+	if len(e.stack) == 0 {
+		return nil
+	}
+	pcs := make([]uintptr, len(e.stack))
+	for i, f := range e.stack {
+		pcs[i] = f.pc
+	}
+	var rv []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		rv = append(rv, frame)
+		if !more {
+			return rv
+		}
+	}
+}
+
+// GetStackFrames returns the stack frames associated with the error if one is
+// found. See (*Error).StackFrames.
+func GetStackFrames(err error) []runtime.Frame {
+	cast, ok := err.(*Error)
+	if !ok {
+		return nil
+	}
+	return cast.StackFrames()
+}
+
+// HasStack reports whether err is an *Error with a captured stack.
+func HasStack(err error) bool {
+	cast, ok := err.(*Error)
+	return ok && cast.HasStack()
+}
+
 // Exits will return the exits recorded on the error if any are found. You
 // probably want the package-level GetExits.
 func (e *Error) Exits() string {
@@ -472,6 +773,29 @@ func GetExits(err error) string {
 	return cast.Exits()
 }
 
+// GetCompactMessage is like GetMessage, but adjacent layers of the same class
+// (as left behind by retry loops that wrap the same class repeatedly) are
+// rendered once with a count, as in "Network Error (x3): dial failed". The
+// error itself is unchanged.
+func GetCompactMessage(err error) string {
+	cast, ok := err.(*Error)
+	if !ok {
+		return GetMessage(err)
+	}
+	count := 1
+	inner := cast.err
+	for {
+		next, ok := inner.(*Error)
+		if !ok || next.class != cast.class {
+			break
+		}
+		count++
+		inner = next.err
+	}
+	message := strings.TrimRight(GetCompactMessage(inner), "\n ")
+	return cast.decorateRepeated(message, count)
+}
+
 // GetMessage returns just the error message without the backtrace or exits.
 func GetMessage(err error) string {
 	if err == nil {
@@ -523,7 +847,11 @@ func (e *ErrorClass) Contains(err error, opts ...EquivalenceOption) bool {
 	if combineEquivOpts(opts)&IncludeWrapped == 0 {
 		return false
 	}
-	return e.Contains(cast.err, opts...)
+	inner := cast.err
+	if caused, ok := inner.(*causedError); ok {
+		inner = caused.cause
+	}
+	return e.Contains(inner, opts...)
 }
 
 var (
@@ -539,11 +867,26 @@ var (
 	// It is not expected that anyone would create instances of these classes.
 	//
 	// from os
-	SyscallError = SystemError.NewClass("Syscall Error")
+	SyscallError     = SystemError.NewClass("Syscall Error")
+	PathError        = SystemError.NewClass("Path Error")
+	LinkError        = SystemError.NewClass("Link Error")
+	NotExistError    = SystemError.NewClass("Not Exist Error")
+	ExistError       = SystemError.NewClass("Exist Error")
+	PermissionError  = SystemError.NewClass("Permission Error")
+	ClosedFileError  = SystemError.NewClass("Closed File Error")
+	TimeoutError     = SystemError.NewClass("Timeout Error", SetPolicy(timeoutPolicy))
+	DeadlineExceeded = TimeoutError.NewClass("Deadline Exceeded Error")
+	ProcessDoneError = SystemError.NewClass("Process Done Error")
+	// from context
+	ContextError         = SystemError.NewClass("Context Error")
+	ContextCanceledError = ContextError.NewClass("Context Canceled Error")
+	ContextDeadlineError = ContextError.NewClass("Context Deadline Error", SetPolicy(timeoutPolicy))
 	// from syscall
 	ErrnoError = SystemError.NewClass("Errno Error")
 	// from net
 	NetworkError        = SystemError.NewClass("Network Error")
+	NetClosedError      = NetworkError.NewClass("Closed Network Connection Error")
+	NetTimeoutError     = NetworkError.NewClass("Network Timeout Error", SetPolicy(timeoutPolicy))
 	UnknownNetworkError = NetworkError.NewClass("Unknown Network Error")
 	AddrError           = NetworkError.NewClass("Addr Error")
 	InvalidAddrError    = AddrError.NewClass("Invalid Addr Error")
@@ -562,44 +905,17 @@ var (
 )
 
 func findSystemErrorClass(err error) *ErrorClass {
-	switch err {
-	case io.EOF:
-		return EOF
-	case io.ErrUnexpectedEOF:
-		return UnexpectedEOFError
-	case io.ErrClosedPipe:
-		return ClosedPipeError
-	case io.ErrNoProgress:
-		return NoProgressError
-	case io.ErrShortBuffer:
-		return ShortBufferError
-	case io.ErrShortWrite:
-		return ShortWriteError
-	default:
-		break
-	}
-	switch err.(type) {
-	case *os.SyscallError:
-		return SyscallError
-	case syscall.Errno:
-		return ErrnoError
-	case net.UnknownNetworkError:
-		return UnknownNetworkError
-	case *net.AddrError:
-		return AddrError
-	case net.InvalidAddrError:
-		return InvalidAddrError
-	case *net.OpError:
-		return NetOpError
-	case *net.ParseError:
-		return NetParseError
-	case *net.DNSError:
-		return DNSError
-	case *net.DNSConfigError:
-		return DNSConfigError
-	case net.Error:
-		return NetworkError
-	default:
-		return SystemError
+	if sanitized, ok := err.(*sanitizedError); ok {
+		return sanitized.class
+	}
+	if scrubbed, ok := err.(*scrubbedError); ok {
+		err = scrubbed.err
+	}
+	if class := runClassifiers(err); class != nil {
+		return class
+	}
+	if class := foreignClass(err); class != nil {
+		return class
 	}
+	return stdClass(err)
 }
@@ -0,0 +1,53 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"testing"
+)
+
+var benchSink error
+
+func BenchmarkNewCaptureStack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSink = HierarchicalError.NewWith("bench")
+	}
+}
+
+func BenchmarkNewNoCaptureStack(b *testing.B) {
+	NoStackError := NewClass("No Stack Error", NoCaptureStack())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSink = NoStackError.NewWith("bench")
+	}
+}
+
+func BenchmarkRenderStack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetStack(HierarchicalError.NewWith("bench"))
+	}
+}
+
+func BenchmarkIsAny(b *testing.B) {
+	err := NetTimeoutError.New("bench")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !IsAny(err, EOF, DNSError, NetworkError) {
+			b.Fatal("no match")
+		}
+	}
+}
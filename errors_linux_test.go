@@ -0,0 +1,45 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFaultPanicIsRecoverable(t *testing.T) {
+	page, err := syscall.Mmap(-1, 0, syscall.Getpagesize(),
+		syscall.PROT_READ, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mprotect(page, syscall.PROT_NONE); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(page)
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	var read byte
+	rec := runtimePanic(func() { read = page[0] })
+	assert(t, read == 0)
+	rerr, ok := rec.(runtime.Error)
+	assert(t, ok && !IsFatalRuntimePanic(rec))
+	assert(t, strings.Contains(rerr.Error(), "invalid memory address"))
+}
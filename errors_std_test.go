@@ -0,0 +1,165 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDecomposeSystemErrors(t *testing.T) {
+	_, openErr := os.Open(filepath.Join(os.TempDir(), "no-such-file-for-errors-test"))
+	err := IOError.Wrap(openErr)
+	assert(t, GetOp(err) == "open")
+	assert(t, strings.HasSuffix(GetPath(err), "no-such-file-for-errors-test"))
+	errno, ok := GetErrno(err)
+	assert(t, ok && errno == syscall.ENOENT)
+	assert(t, GetData(err, PathKey) == GetPath(err))
+	assert(t, GetPath(ProgrammerError.Wrap(err)) == GetPath(err))
+
+	link := &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EEXIST}
+	err = SystemError.Wrap(link, SetData(PathKey, "override"))
+	assert(t, GetOp(err) == "rename")
+	assert(t, GetPath(err) == "override")
+	assert(t, GetLinkTarget(err) == "b")
+
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}
+	opErr := &net.OpError{Op: "dial", Net: "tcp", Addr: addr,
+		Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	err = NetworkError.Wrap(fmt.Errorf("fetching: %w", opErr))
+	assert(t, GetOp(err) == "dial")
+	assert(t, GetAddr(err) == "127.0.0.1:9")
+	errno, ok = GetErrno(err)
+	assert(t, ok && errno == syscall.ECONNREFUSED)
+	assert(t, GetAddr(opErr) == "127.0.0.1:9")
+
+	_, ok = GetErrno(ProgrammerError.New("plain"))
+	assert(t, !ok && GetOp(ProgrammerError.New("plain")) == "")
+}
+
+func TestRecordGoroutine(t *testing.T) {
+	WorkerError := NewClass("Worker Error", RecordGoroutine(),
+		NoCaptureStack())
+	PipelineError := NewClass("Pipeline Error", NoCaptureStack())
+
+	results := make(chan error)
+	go pprof.Do(context.Background(), pprof.Labels("worker", "7"),
+		func(ctx context.Context) {
+			results <- WorkerError.NewWith("failed", GoroutineLabels(ctx)...)
+		})
+	err := PipelineError.Wrap(<-results)
+
+	here, _ := goroutineID()
+	worker, ok := GetGoroutine(err)
+	assert(t, ok && worker != 0 && worker != here)
+	assert(t, GetGoroutineLabels(err)["worker"] == "7")
+
+	_, ok = GetGoroutine(New("unrecorded"))
+	assert(t, !ok && GetGoroutineLabels(New("unlabeled")) == nil)
+}
+
+func TestSystemErrorClassification(t *testing.T) {
+	assert(t, GetClass(io.EOF) == EOF)
+	assert(t, GetClass(net.ErrClosed) == NetClosedError)
+	assert(t, GetClass(context.DeadlineExceeded) == ContextDeadlineError)
+	assert(t, GetClass(&os.PathError{Op: "open", Err: os.ErrNotExist}) ==
+		PathError)
+	assert(t, TimeoutError.Contains(os.ErrDeadlineExceeded))
+
+	classifiers.mu.RLock()
+	registered := classifiers.list
+	classifiers.mu.RUnlock()
+	t.Cleanup(func() {
+		classifiers.mu.Lock()
+		classifiers.list = registered
+		classifiers.mu.Unlock()
+	})
+	custom := fmt.Errorf("custom")
+	CustomError := SystemError.NewClass("Custom Error")
+	RegisterClassifier(func(err error) *ErrorClass {
+		if err == custom {
+			return CustomError
+		}
+		return nil
+	})
+	assert(t, GetClass(custom) == CustomError)
+	assert(t, GetClass(fmt.Errorf("other")) == SystemError)
+}
+
+func TestTimeoutFamily(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	write := &net.OpError{Op: "write", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	request := &url.Error{Op: "Get", URL: "http://x", Err: context.DeadlineExceeded}
+
+	assert(t, GetClass(dial) == DialTimeoutError)
+	assert(t, GetClass(read) == ReadTimeoutError)
+	assert(t, GetClass(write) == NetOpTimeoutError)
+	assert(t, GetClass(refused) == NetOpError)
+	assert(t, GetClass(request) == RequestTimeoutError)
+	// timeouts stay in the network tree they were classified into before.
+	for _, err := range []error{dial, read, write} {
+		assert(t, NetOpError.Contains(err) && NetworkError.Contains(err))
+	}
+	assert(t, NetworkError.Contains(request))
+
+	lock := LockTimeoutError.NewWith("lock on users",
+		TimeoutAfter(time.Second, 1500*time.Millisecond))
+	limit, ok := GetTimeoutLimit(lock)
+	assert(t, ok && limit == time.Second)
+	elapsed, ok := GetDuration(lock)
+	assert(t, ok && elapsed == 1500*time.Millisecond)
+
+	for _, err := range []error{dial, write, request, lock,
+		context.DeadlineExceeded} {
+		assert(t, IsTimeout(err))
+		assert(t, Retryable(err))
+		assert(t, GetPolicy(err).HTTPStatus == 504)
+	}
+	assert(t, IsTimeout(NetworkError.Wrap(read)))
+	assert(t, !IsTimeout(refused) && !Retryable(refused))
+	assert(t, !IsTimeout(nil))
+}
+
+func TestSanitizeKeepsSystemClass(t *testing.T) {
+	path := Sanitize(&os.PathError{
+		Op: "open", Path: "/srv/secrets/db.conf", Err: os.ErrNotExist})
+	assert(t, path.Error() == "open <path>: file does not exist")
+	assert(t, GetClass(path) == PathError)
+	assert(t, SystemError.Contains(path))
+
+	network := Sanitize(&net.OpError{Op: "dial", Net: "tcp", Err: io.EOF})
+	assert(t, NetworkError.Contains(network))
+	assert(t, GetClass(network) == GetClass(&net.OpError{
+		Op: "dial", Net: "tcp", Err: io.EOF}))
+
+	wrapped := Sanitize(NewClass("Open Error").Wrap(&os.PathError{
+		Op: "open", Path: "/srv/secrets/db.conf", Err: os.ErrNotExist}))
+	assert(t, PathError.Contains(wrapped, IncludeWrapped))
+	assert(t, !strings.Contains(GetMessage(wrapped), "/srv/secrets"))
+}
@@ -16,16 +16,39 @@ package errors
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+	"unicode/utf8"
+	"weak"
 )
 
 var (
 	logbuf = new(bytes.Buffer)
+
+	uniques uint32
 )
 
+// unique suffixes name so each call gets a different class path or id, and
+// tests stay independent when run with -count.
+func unique(name string) string {
+	return fmt.Sprintf("%s #%d", name, atomic.AddUint32(&uniques, 1))
+}
+
 func init() {
 	log.SetFlags(0)
 	log.SetOutput(logbuf)
@@ -145,6 +168,13 @@ func TestLoggingErrorGroupLogsAndReturnsErrIfAdded(t *testing.T) {
 	}
 }
 
+// needStacks skips tests of stack capture in builds without it.
+func needStacks(t *testing.T) {
+	if !stackSupport {
+		t.Skip("no stack capture in this build")
+	}
+}
+
 func assert(t *testing.T, val bool) {
 	if !val {
 		t.Fatal("assertion failed")
@@ -212,3 +242,1697 @@ func ExampleErrorGroup(t *testing.T) {
 		handle_err(err)
 	}
 }
+
+func TestStampBuildInfo(t *testing.T) {
+	StampedError := NewClass("Stamped Error", StampBuildInfo())
+	err := StampedError.New("stamped")
+	assert(t, GetBuildInfo(err) == currentBuildInfo())
+	assert(t, GetBuildInfo(New("unstamped")) == nil)
+}
+
+func TestNamespacedClassesIntern(t *testing.T) {
+	// simulate two copies of the same package declaring the same classes
+	ns := unique("example.com/shared")
+	V1Error := NewClass("Shared Error", Namespace(ns))
+	V1NotFound := V1Error.NewClass("Not Found")
+	V2Error := NewClass("Shared Error", Namespace(ns))
+	V2NotFound := V2Error.NewClass("Not Found")
+
+	assert(t, V1NotFound.Path() == ns+":Error/Shared Error/Not Found")
+	assert(t, V1NotFound.Contains(V2NotFound.New("missing")))
+	assert(t, V2Error.Contains(V1NotFound.New("missing")))
+	assert(t, LookupClass(V2NotFound.Path()) == V1NotFound)
+
+	// classes outside a namespace are never merged
+	Local1 := NewClass("Local Error")
+	Local2 := NewClass("Local Error")
+	assert(t, !Local1.Contains(Local2.New("local")))
+	assert(t, LookupClass(Local2.Path()) == Local2)
+	assert(t, ClassForID(ClassID(Local1)) == Local2)
+}
+
+func TestRecorderKeepsLastErrors(t *testing.T) {
+	needStacks(t)
+	rec := NewRecorder(2)
+	assert(t, len(rec.Snapshot()) == 0)
+	rec.Add(New("first"))
+	rec.Add(nil)
+	rec.Add(New("second"))
+	rec.Add(New("third"))
+	snap := rec.Snapshot()
+	assert(t, len(snap) == 2)
+	assert(t, snap[0].Message == "Error: second")
+	assert(t, snap[1].Message == "Error: third")
+	assert(t, len(snap[1].Stack) > 0 && len(snap[1].Stack) <= recordedFrames)
+}
+
+func TestClassStatsCounts(t *testing.T) {
+	CountedError := NewClass("Counted Error")
+	Caught(CountedError.New("one"))
+	CountedError.New("two")
+	stats := CountedError.Stats()
+	assert(t, stats.Created == 2)
+	assert(t, stats.Caught == 1)
+	assert(t, stats.Parent == "Error")
+	assert(t, stats.CaptureStack)
+}
+
+func TestEmitReceiveRecordsExits(t *testing.T) {
+	ch := make(chan error, 1)
+	go Emit(ch, New("across"))
+	err, ok := Receive(ch)
+	assert(t, ok)
+	exits := GetExits(err)
+	assert(t, strings.Contains(exits, "(sent on channel)"))
+	assert(t, strings.Contains(exits, "TestEmitReceiveRecordsExits"))
+	assert(t, strings.Contains(exits, "(received from channel)"))
+}
+
+func TestJournalOnCreation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errors-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "errors.log")
+	j, err := OpenJournal(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	SetJournal(j)
+	defer SetJournal(nil)
+
+	JournaledError := NewClass("Journaled Error", JournalOnCreation())
+	JournaledError.New("first")
+	JournaledError.New("second")
+	New("not journaled")
+
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := ioutil.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, strings.Contains(string(rotated), `"message":"Journaled Error: first"`))
+	assert(t, strings.Contains(string(current), `"message":"Journaled Error: second"`))
+	assert(t, strings.Count(string(current), "\n") == 1)
+}
+
+func TestJournalRotationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errors-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "errors.log")
+	j, err := OpenJournal(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	RotatedError := NewClass("Rotated Error")
+	assert(t, j.Write(RotatedError.New("first")) == nil)
+
+	// a non-empty directory in the way of the backup fails the rotation.
+	blocker := filepath.Join(path+".1", "blocker")
+	if err := os.MkdirAll(blocker, 0755); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, j.Write(RotatedError.New("second")) != nil)
+
+	// once it's out of the way, the journal recovers.
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, j.Write(RotatedError.New("third")) == nil)
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, strings.Contains(string(current), "Rotated Error: third"))
+
+	assert(t, j.Close() == nil)
+	assert(t, ProgrammerError.Contains(j.Write(RotatedError.New("fourth"))))
+}
+
+func TestGetRouteMergesHierarchy(t *testing.T) {
+	StorageError := NewClass("Storage Error",
+		SetRoute(Route{Team: "storage", Service: "storage-pager"}))
+	DiskError := StorageError.NewClass("Disk Error",
+		SetRoute(Route{Channel: "#disks"}))
+	err := DiskError.NewWith("full", SetRoute(Route{Service: "disk-pager"}))
+	assert(t, GetRoute(err) == Route{
+		Team: "storage", Service: "disk-pager", Channel: "#disks"})
+	assert(t, GetRoute(New("plain")) == Route{})
+
+	VolumeError := DiskError.NewClass("Volume Error", DisableInheritance(),
+		SetRoute(Route{Team: "volumes"}))
+	assert(t, GetRoute(VolumeError.New("gone")) == Route{Team: "volumes"})
+	assert(t, GetRoute(DiskError.NewWith("x", DisableInheritance(),
+		SetRoute(Route{Team: "disks"}))) == Route{Team: "disks"})
+}
+
+func TestAssertEqual(t *testing.T) {
+	failing := func() (err error) {
+		defer CatchPanic(&err)
+		Assert(1+1 == 2, "arithmetic")
+		AssertEqual([]int{1, 2}, []int{1, 3})
+		return nil
+	}
+	err := failing()
+	assert(t, AssertionError.Contains(err, IncludeWrapped))
+	assertion := WrappedErr(err)
+	assert(t, GetData(assertion, ActualKey).([]int)[1] == 3)
+	assert(t, strings.HasPrefix(GetMessage(assertion),
+		"Assertion Error: expected []int{1, 2}, got []int{1, 3}"))
+}
+
+func TestFindType(t *testing.T) {
+	pathErr := &os.PathError{Op: "open", Path: "/nope", Err: os.ErrNotExist}
+	err := NewClass("Outer Error").Wrap(
+		fmt.Errorf("context: %w", HierarchicalError.Wrap(pathErr)))
+
+	found, ok := FindType[*os.PathError](err)
+	assert(t, ok && found == pathErr)
+	_, ok = FindType[*os.LinkError](err)
+	assert(t, !ok)
+}
+
+func TestMessagePrefix(t *testing.T) {
+	UserError := NewClass("User Error", OmitClassName())
+	TaggedError := NewClass("Tagged Error", SetMessagePrefix("[{class}] "))
+	assert(t, GetMessage(UserError.New("bad input")) == "bad input")
+	assert(t, GetMessage(UserError.New("bad\ninput")) == "bad\n  input")
+	assert(t, GetMessage(TaggedError.New("oops")) == "[Tagged Error] oops")
+	assert(t, GetMessage(TaggedError.New("oops\nagain")) ==
+		"[Tagged Error]\n  oops\n  again")
+	assert(t, GetMessage(New("plain")) == "Error: plain")
+}
+
+func TestPooledErrorUseAfterRelease(t *testing.T) {
+	Config.Debugpool = true
+	defer func() { Config.Debugpool = false }()
+
+	PooledError := NewClass("Pooled Error", Pooled(), NoCaptureStack())
+	err := PooledError.NewWith("transient", SetData(ExpectedKey, 1))
+	assert(t, GetMessage(err) == "Pooled Error: transient")
+	err.(*Error).Release()
+
+	used := func() (rv error) {
+		defer CatchPanic(&rv)
+		GetMessage(err)
+		return nil
+	}()
+	assert(t, ProgrammerError.Contains(used, IncludeWrapped))
+}
+
+func TestExport(t *testing.T) {
+	Config.Debugexport = true
+	defer func() { Config.Debugexport = false }()
+
+	err := New("shipped")
+	AttachData(err, ExpectedKey, 1)
+	x := Export(err)
+	assert(t, IsExported(err) && !IsExported(New("fresh")))
+	assert(t, x.Class() == HierarchicalError &&
+		x.Message() == "Error: shipped")
+	assert(t, x.GetData(ExpectedKey) == 1)
+
+	modified := func(modify func()) (rv error) {
+		defer CatchPanic(&rv)
+		modify()
+		return nil
+	}
+	assert(t, ProgrammerError.Contains(modified(func() {
+		AttachData(err, ExpectedKey, 2)
+	}), IncludeWrapped))
+	// exits and annotations are bookkeeping, not modifications.
+	assert(t, modified(func() { Record(err) }) == nil)
+	assert(t, modified(func() { Annotate(err, ActualKey, 1) }) == nil)
+	assert(t, modified(func() { RecordDuration(err, time.Second) }) == nil)
+	assert(t, x.GetData(ActualKey) == nil)
+
+	// the view doesn't change with the error, and clones can be modified.
+	Config.Debugexport = false
+	AttachData(err, ExpectedKey, 3)
+	assert(t, x.GetData(ExpectedKey) == 1)
+	clone := Clone(err)
+	assert(t, modified(func() { AttachData(clone, ExpectedKey, 4) }) == nil)
+}
+
+func TestPooledErrorReuse(t *testing.T) {
+	PooledError := NewClass("Reused Error", Pooled())
+	for i := 0; i < 3; i++ {
+		err := PooledError.NewWith(fmt.Sprint(i), SetData(ExpectedKey, i))
+		assert(t, GetMessage(err) == fmt.Sprintf("Reused Error: %d", i))
+		assert(t, GetData(err, ExpectedKey) == i)
+		assert(t, GetData(err, ActualKey) == nil)
+		err.(*Error).Release()
+	}
+	// stack state doesn't carry over to the error's next use.
+	lazy := NewClass("Reused Lazy Error", Pooled(), CaptureStack(),
+		DeferStackCapture())
+	err := lazy.New("lazy").(*Error)
+	err.fakeStack = []string{"stale"}
+	err.Release()
+	assert(t, err.fakeStack == nil && !err.stackDeferred &&
+		len(err.stack) == 0 && err.stackText == "")
+
+	// errors from other classes are unaffected
+	plain := New("plain")
+	plain.(*Error).Release()
+	assert(t, GetMessage(plain) == "Error: plain")
+}
+
+func TestSanitize(t *testing.T) {
+	needStacks(t)
+	publicKey, privateKey := GenSym(), GenSym()
+	MarkPublicData(publicKey)
+	OpenError := NewClass("Open Error")
+	original := OpenError.Wrap(&os.PathError{
+		Op: "open", Path: "/srv/secrets/db.conf", Err: os.ErrNotExist},
+		SetData(publicKey, "public"), SetData(privateKey, "private"))
+	Record(original)
+
+	clean := Sanitize(original)
+	assert(t, OpenError.Contains(clean))
+	assert(t, GetMessage(clean) ==
+		"Open Error: open <path>: file does not exist")
+	assert(t, GetStack(clean) == "" && GetExits(clean) == "")
+	assert(t, GetData(clean, publicKey) == "public")
+	assert(t, GetData(clean, privateKey) == nil)
+	assert(t, GetStack(original) != "" && GetExits(original) != "")
+}
+
+func TestSanitizeUserFacing(t *testing.T) {
+	QuotaError := NewClass("Sanitize Quota Error")
+	err := QuotaError.NewWith("bucket full", UserFacing(),
+		SetMessagePrefix("[{class}] "))
+	clean := Sanitize(err)
+	assert(t, IsUserFacing(clean))
+	assert(t, GetMessage(clean) == "[Sanitize Quota Error] bucket full")
+	assert(t, PublicMessage(clean) == PublicMessage(err))
+
+	internal := Sanitize(NewClass("Sanitize Public Error", UserFacing()).
+		NewWith("oops", Internal()))
+	assert(t, !IsUserFacing(internal))
+	assert(t, strings.HasPrefix(PublicMessage(internal), "internal error"))
+}
+
+func TestPublicMessage(t *testing.T) {
+	BadRequest := NewClass("Bad Request", UserFacing())
+	assert(t, PublicMessage(BadRequest.New("missing field")) ==
+		"Bad Request: missing field")
+	internal := New("database exploded")
+	assert(t, PublicMessage(internal) ==
+		fmt.Sprintf("internal error (id: %s)", GetID(internal)))
+	assert(t, GetID(internal) == GetID(internal))
+	assert(t, GetID(internal) != GetID(New("another")))
+	assert(t, PublicMessage(fmt.Errorf("plain")) == "internal error")
+}
+
+func TestGetCompactMessage(t *testing.T) {
+	RetryError := NewClass("Retry Error")
+	err := fmt.Errorf("dial failed")
+	for i := 0; i < 3; i++ {
+		err = RetryError.Wrap(err, SetData(ExpectedKey, i))
+	}
+	err = NewClass("Outer Error").Wrap(err)
+	assert(t, GetCompactMessage(err) ==
+		"Outer Error: Retry Error (x3): dial failed")
+	assert(t, GetMessage(err) ==
+		"Outer Error: Retry Error: Retry Error: Retry Error: dial failed")
+}
+
+// runtimePanic returns what recover returns for the panic f raises.
+func runtimePanic(f func()) (rec interface{}) {
+	defer func() { rec = recover() }()
+	f()
+	return nil
+}
+
+func TestFatalRuntimePanic(t *testing.T) {
+	var nilMap map[string]int
+	var value interface{} = 1
+	zero, index := 0, 3
+	panics := []interface{}{
+		runtimePanic(func() { _ = *(*int)(nil) }),
+		runtimePanic(func() { _ = []int{}[index] }),
+		runtimePanic(func() { _ = 1 / zero }),
+		runtimePanic(func() { nilMap["key"] = 1 }),
+		runtimePanic(func() { _ = value.(string) }),
+	}
+	// none of the runtime's recoverable panics are fatal by default.
+	for _, rec := range panics {
+		_, ok := rec.(runtime.Error)
+		assert(t, ok && !IsFatalRuntimePanic(rec))
+	}
+	assert(t, !IsFatalRuntimePanic(fmt.Errorf("integer divide by zero")))
+
+	defer func(patterns []string) { FatalRuntimePatterns = patterns }(
+		FatalRuntimePatterns)
+	FatalRuntimePatterns = []string{"integer divide by zero"}
+	assert(t, IsFatalRuntimePanic(panics[2]))
+	assert(t, !IsFatalRuntimePanic(panics[1]))
+
+	catch := func() (err error) {
+		defer CatchPanic(&err)
+		_ = 1 / zero
+		return nil
+	}
+	func() {
+		defer func() { assert(t, IsFatalRuntimePanic(recover())) }()
+		catch()
+		t.Fatal("CatchPanic consumed a fatal runtime panic")
+	}()
+
+	Config.Catchfatal = true
+	defer func() { Config.Catchfatal = false }()
+	assert(t, FatalRuntimeError.Contains(catch()))
+}
+
+type testTraceKey struct{}
+
+func TestNewCtxHarvestsTrace(t *testing.T) {
+	SetTraceExtractor(func(ctx context.Context) TraceInfo {
+		trace, _ := ctx.Value(testTraceKey{}).(TraceInfo)
+		return trace
+	}, "tenant")
+	defer SetTraceExtractor(nil)
+
+	ctx := context.WithValue(context.Background(), testTraceKey{}, TraceInfo{
+		TraceID: "abc", SpanID: "def",
+		Baggage: map[string]string{"tenant": "t1", "secret": "s"}})
+	err := HierarchicalError.NewCtx(ctx, "failed %d", 1)
+	trace, ok := GetTraceInfo(err)
+	assert(t, ok && trace.TraceID == "abc" && trace.SpanID == "def")
+	assert(t, len(trace.Baggage) == 1 && trace.Baggage["tenant"] == "t1")
+	assert(t, GetMessage(err) == "Error: failed 1")
+
+	_, ok = GetTraceInfo(HierarchicalError.WrapCtx(context.Background(),
+		fmt.Errorf("untraced")))
+	assert(t, !ok)
+}
+
+type quotaExceeded struct{ Limit, Used int }
+
+func TestTypedClass(t *testing.T) {
+	QuotaError := NewTypedClass[quotaExceeded](NewClass("Limit Error"),
+		"Quota Error", "used {{.Used}} of {{.Limit}}")
+	err := QuotaError.New(quotaExceeded{Limit: 10, Used: 12})
+	assert(t, GetMessage(err) == "Quota Error: used 12 of 10")
+	assert(t, QuotaError.Contains(err))
+
+	fields, ok := QuotaError.Fields(PanicError.Wrap(err))
+	assert(t, ok && fields.Limit == 10 && fields.Used == 12)
+	_, ok = QuotaError.Fields(New("other"))
+	assert(t, !ok)
+}
+
+func TestLogWithStackDedup(t *testing.T) {
+	Config.Logdedupwindow = time.Hour
+	t.Cleanup(func() {
+		Config.Logdedupwindow = 0
+		dedup.Lock()
+		clear(dedup.seen)
+		dedup.Unlock()
+	})
+	logbuf.Reset()
+
+	err := New("noisy")
+	for i := 0; i < 3; i++ {
+		LogWithStack(err)
+	}
+	LogWithStack(New("noisy"))
+	LogWithStack("other")
+	logged := logbuf.String()
+	assert(t, strings.Count(logged, "Error: noisy") == 1)
+	assert(t, strings.Count(logged, "other") == 1)
+
+	// once the window passes, the next log reports the suppressed repeats
+	dedup.seen[dedupKey([]interface{}{err})].first =
+		time.Now().Add(-2 * time.Hour)
+	LogWithStack(err)
+	assert(t, strings.Contains(logbuf.String(), "(seen 3 more times)"))
+
+	for i := 0; i < 2*maxDedupEntries; i++ {
+		shouldLog([]interface{}{i})
+	}
+	assert(t, len(dedup.seen) <= maxDedupEntries)
+}
+
+func TestJourney(t *testing.T) {
+	needStacks(t)
+	inner := HierarchicalError.New("inner")
+	Record(inner)
+	outer := NewClass("Outer Error").WrapUnless(inner)
+	ch := make(chan error, 1)
+	Emit(ch, outer)
+	outer, _ = Receive(ch)
+
+	journey := Journey(outer)
+	kinds := make([]string, 0, len(journey))
+	for _, event := range journey {
+		kinds = append(kinds, event.Kind+":"+event.Label)
+	}
+	assert(t, strings.Join(kinds, ",") == "created:,exited:,wrapped:,"+
+		"exited:sent on channel,exited:received from channel")
+	assert(t, journey[0].Class == "Error")
+	assert(t, strings.Contains(journey[0].Location, "TestJourney"))
+	assert(t, journey[2].Class == "Error/Outer Error")
+
+	journeyKinds := func(err error) string {
+		var kinds []string
+		for _, event := range Journey(err) {
+			kinds = append(kinds, event.Kind)
+		}
+		return strings.Join(kinds, ",")
+	}
+	TravelError := NewClass(unique("Travel Error"))
+
+	// serializing leaves errors alone unless journeys are asked for.
+	quiet := TravelError.New("quiet")
+	data, merr := MarshalError(quiet)
+	if merr != nil {
+		t.Fatal(merr)
+	}
+	assert(t, journeyKinds(quiet) == "created")
+	assert(t, !strings.Contains(string(data), "journey"))
+
+	Config.Journeys = true
+	defer func() { Config.Journeys = false }()
+	sent := TravelError.New("sent")
+	data, merr = MarshalError(sent)
+	if merr != nil {
+		t.Fatal(merr)
+	}
+	received, uerr := UnmarshalError(data)
+	if uerr != nil {
+		t.Fatal(uerr)
+	}
+	assert(t, journeyKinds(sent) == "created,serialized")
+	assert(t, journeyKinds(received) == "created,serialized,deserialized")
+	last := Journey(received)[2]
+	assert(t, last.Class == TravelError.Path() &&
+		strings.Contains(last.Location, "TestJourney"))
+
+	buf, _ := EncodeBinary(sent)
+	view, _ := ParseBinary(buf)
+	rehydrated, _ := view.Rehydrate()
+	assert(t, journeyKinds(sent) == "created,serialized,serialized")
+	assert(t, strings.HasSuffix(journeyKinds(rehydrated), ",deserialized"))
+
+	// errors serialized over and over keep only their latest travels.
+	for i := 0; i < 3*maxTravels; i++ {
+		MarshalError(sent)
+	}
+	assert(t, len(Journey(sent)) == 1+maxTravels)
+}
+
+func TestForTest(t *testing.T) {
+	logbuf.Reset()
+	key := GenSym()
+	err := ProgrammerError.ForTest("fabricated",
+		[]string{"main.main:main.go:10"}, SetData(key, 5))
+	assert(t, GetStack(err) == "main.main:main.go:10")
+	assert(t, GetData(err, key) == 5)
+	assert(t, ProgrammerError.Contains(err))
+	assert(t, logbuf.Len() == 0)
+	assert(t, GetStack(ProgrammerError.ForTest("no stack", nil)) == "")
+}
+
+func TestMetricLabel(t *testing.T) {
+	FruitError := NewClass("Fruit Error")
+	AppleError := FruitError.NewClass("Apple Error")
+	assert(t, MetricLabel(AppleError.New("x %d", 1)) == "Error/Fruit Error")
+	assert(t, MetricLabel(FruitError.New("y")) == "Error/Fruit Error")
+	assert(t, MetricLabel(New("z")) == "Error")
+	assert(t, MetricLabel(io.EOF) == "System Error/IO Error")
+	assert(t, MetricLabel(nil) == "")
+
+	MetricLabelDepth = 2
+	defer func() { MetricLabelDepth = 1 }()
+	assert(t, MetricLabel(AppleError.New("x")) ==
+		"Error/Fruit Error/Apple Error")
+}
+
+func TestScrubbers(t *testing.T) {
+	emails := regexp.MustCompile(`[\w.]+@[\w.]+`)
+	tokens := regexp.MustCompile(`token=\w+`)
+	scrubbers.mu.RLock()
+	registered := scrubbers.list
+	scrubbers.mu.RUnlock()
+	t.Cleanup(func() {
+		scrubbers.mu.Lock()
+		scrubbers.list = registered
+		scrubbers.mu.Unlock()
+	})
+	RegisterScrubber(RegexpScrubber(emails, "<email>"))
+	AuthError := NewClass("Auth Error",
+		ScrubWith(RegexpScrubber(tokens, "token=<redacted>")))
+
+	vendor := fmt.Errorf("user bob@example.com rejected, token=abc123")
+	err := AuthError.Wrap(vendor)
+	assert(t, GetMessage(err) ==
+		"Auth Error: user <email> rejected, token=<redacted>")
+	assert(t, !strings.Contains(err.Error(), "bob@example.com"))
+	assert(t, GetMessage(New("contact ops@example.com")) ==
+		"Error: contact <email>")
+	assert(t, walk(err, func(e error) bool { return e == vendor }))
+	assert(t, EOF.Contains(AuthError.Wrap(io.EOF), IncludeWrapped))
+}
+
+func TestSymbolizeAsyncMemoizes(t *testing.T) {
+	needStacks(t)
+	err := HierarchicalError.New("slow")
+	SymbolizeAsync(err)
+	first := GetStack(err)
+	assert(t, strings.Contains(first, "TestSymbolizeAsyncMemoizes"))
+	assert(t, GetStack(err) == first)
+
+	// pooled errors may be reused while queued, so they aren't queued.
+	pooled := NewClass("Symbolized Pooled Error", Pooled())
+	for i := 0; i < 100; i++ {
+		err := pooled.New("recycled")
+		SymbolizeAsync(err)
+		err.(*Error).Release()
+	}
+}
+
+func TestPolicyNearestAncestor(t *testing.T) {
+	NetworkError := NewClass("Network Error", SetPolicy(Policy{
+		Retryable: PolicyYes, MaxRetries: 3, Report: "sentry", HTTPStatus: 503}))
+	DNSError := NetworkError.NewClass("DNS Error", SetPolicy(Policy{
+		MaxRetries: 5}))
+	BadHostError := DNSError.NewClass("Bad Host Error", SetPolicy(Policy{
+		Retryable: PolicyNo, HTTPStatus: 400}))
+
+	assert(t, GetPolicy(DNSError.New("slow")) == Policy{
+		Retryable: PolicyYes, MaxRetries: 5, Report: "sentry", HTTPStatus: 503})
+	assert(t, Retryable(DNSError.New("slow")))
+	assert(t, !Retryable(BadHostError.New("nope")))
+	assert(t, BadHostError.Policy().MaxRetries == 5)
+	assert(t, GetPolicy(BadHostError.NewWith("x",
+		SetPolicy(Policy{Report: "log"}))).Report == "log")
+	assert(t, GetPolicy(fmt.Errorf("plain")) == Policy{})
+
+	IsolatedError := DNSError.NewClass("Isolated Error", DisableInheritance(),
+		SetPolicy(Policy{Report: "log"}))
+	assert(t, IsolatedError.Policy() == Policy{Report: "log"})
+	assert(t, IsolatedError.NewClass("Isolated Child").Policy().Report == "log")
+	assert(t, GetPolicy(DNSError.NewWith("x", DisableInheritance())) == Policy{})
+}
+
+func TestGenerateClassRefs(t *testing.T) {
+	StorageError := NewClass("storage error")
+	DiskError := StorageError.NewClass("disk-full error")
+	var buf bytes.Buffer
+	err := generateClassRefs(&buf, "errclasses",
+		[]*ErrorClass{StorageError, DiskError}, []string{"example.com/storage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	assert(t, strings.Contains(src, "package errclasses"))
+	assert(t, strings.Contains(src, `_ "example.com/storage"`))
+	assert(t, strings.Contains(src,
+		`StorageErrorDiskFullError = errors.LookupClass("Error/storage error/disk-full error")`))
+
+	err = generateClassRefs(&buf, "errclasses", []*ErrorClass{
+		StorageError, NewClass("storage error"), NewClass("Storage-Error")}, nil)
+	assert(t, DuplicateClassError.Contains(err))
+	assert(t, strings.Contains(err.Error(), `"Error/storage error" declared 2 times`))
+	assert(t, strings.Contains(err.Error(), "both generate StorageError"))
+}
+
+func TestABIRoundTrip(t *testing.T) {
+	BridgeError := NewClass("Bridge Error")
+	LibError := BridgeError.NewClass("Lib Error")
+	codeKey := GenSym()
+	ExportDataKey("code", codeKey)
+
+	id, message, data, err := ExportABI(LibError.NewWith("bad handle",
+		SetData(codeKey, 7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, id != 0 && id == ClassID(LibError))
+	assert(t, message == "bad handle")
+
+	imported, err := ImportABI(id, message, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, LibError.Contains(imported))
+	assert(t, GetMessage(imported) == "Lib Error: bad handle")
+	assert(t, GetData(imported, codeKey) == float64(7))
+
+	id, message, _, _ = ExportABI(fmt.Errorf("plain"))
+	assert(t, id == 0 && message == "plain")
+	imported, _ = ImportABI(id, message, nil)
+	assert(t, GetClass(imported) == HierarchicalError)
+}
+
+func TestEncodeBounded(t *testing.T) {
+	needStacks(t)
+	RPCError := NewClass("RPC Error")
+	sizeKey := GenSym()
+	ExportDataKey("size", sizeKey)
+	err := RPCError.NewWith(strings.Repeat("é", 200), SetData(sizeKey, 1))
+
+	out, dropped, eerr := EncodeBounded(err, 1<<20)
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	assert(t, len(dropped) == 0)
+	var full EncodedError
+	if eerr := json.Unmarshal(out, &full); eerr != nil {
+		t.Fatal(eerr)
+	}
+	assert(t, len(full.Stack) > 0 && full.Data["size"] == float64(1))
+
+	for _, limit := range []int{len(out) - 1, 450, 200, 90} {
+		out, dropped, eerr = EncodeBounded(err, limit)
+		if eerr != nil {
+			t.Fatal(eerr)
+		}
+		assert(t, len(out) <= limit)
+		var enc EncodedError
+		if eerr := json.Unmarshal(out, &enc); eerr != nil {
+			t.Fatal(eerr)
+		}
+		assert(t, dropped[0] == DroppedStack && enc.Stack == nil)
+		assert(t, strings.Join(enc.Dropped, ",") == strings.Join(dropped, ","))
+		assert(t, utf8.ValidString(enc.Message))
+	}
+	assert(t, dropped[len(dropped)-1] == DroppedMessage)
+
+	_, _, eerr = EncodeBounded(err, 10)
+	assert(t, EncodeBudgetError.Contains(eerr))
+}
+
+func TestDescribeClasses(t *testing.T) {
+	type Quota struct{ Used, Limit int }
+	QuotaError := NewTypedClass[Quota](NewClass("Describe Limit Error",
+		UserFacing(), SetPolicy(Policy{HTTPStatus: 429})),
+		"Describe Quota Error", "used {{.Used}} of {{.Limit}}")
+	SecretError := NewClass("Describe Secret Error",
+		MessageTemplate("key {{.Key}} leaked"))
+	ErrShort := Define[int](QuotaError.ErrorClass, "Describe Short Error",
+		"short by %d")
+
+	var quota, secret, short *ClassDescriptor
+	descs := DescribeClasses()
+	for i := range descs {
+		switch descs[i].Path {
+		case QuotaError.Path():
+			quota = &descs[i]
+		case SecretError.Path():
+			secret = &descs[i]
+		case ErrShort.Path():
+			short = &descs[i]
+		}
+	}
+	assert(t, quota != nil && secret != nil && short != nil)
+	assert(t, short.Format == "short by %d" && short.Template == "")
+	assert(t, *quota == ClassDescriptor{
+		ID:         ClassID(QuotaError.ErrorClass),
+		Code:       "DescribeLimitErrorDescribeQuotaError",
+		Path:       "Error/Describe Limit Error/Describe Quota Error",
+		Parent:     "Error/Describe Limit Error",
+		UserFacing: true,
+		Template:   "used {{.Used}} of {{.Limit}}",
+		HTTPStatus: 429})
+	assert(t, !secret.UserFacing && secret.Template == "")
+
+	var buf bytes.Buffer
+	if err := WriteClassDescriptors(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, strings.Contains(buf.String(),
+		`"code": "DescribeLimitErrorDescribeQuotaError"`))
+}
+
+func TestFreezeRegistry(t *testing.T) {
+	PluginError := NewClass("Freeze Plugin Error", Dynamic())
+	FreezeRegistry()
+	defer atomic.StoreUint32(&frozen, 0)
+
+	PluginError.NewClass("Freeze Late Plugin Error")
+
+	var logged string
+	defer func(old func(string, ...interface{})) { LogMethod = old }(LogMethod)
+	LogMethod = func(format string, args ...interface{}) {
+		logged += fmt.Sprintf(format, args...)
+	}
+	err := func() (err error) {
+		defer CatchPanic(&err)
+		NewClass("Freeze Late Error")
+		return nil
+	}()
+	assert(t, ProgrammerError.Contains(err, IncludeWrapped))
+
+	FrozenRegistryPanics = false
+	defer func() { FrozenRegistryPanics = true }()
+	logged = ""
+	NewClass("Freeze Logged Error")
+	assert(t, strings.Contains(logged, `"Error/Freeze Logged Error" created after FreezeRegistry`))
+}
+
+func TestPluginUnload(t *testing.T) {
+	HostError := NewClass("Plugin Host Error")
+	plugin, err := LoadPlugin("thumbnailer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadPlugin("thumbnailer")
+	assert(t, ProgrammerError.Contains(err))
+
+	ResizeError := plugin.Root().NewClass("Resize Error")
+	DecodeError := plugin.NewClass(HostError, "Decode Error")
+	path := ResizeError.Path()
+	assert(t, LookupClass(path) == ResizeError)
+
+	resizeErr := ResizeError.New("too big")
+	decodeErr := DecodeError.New("bad png")
+	plugin.Unload()
+
+	assert(t, LookupClass(path) == nil)
+	for _, class := range Classes() {
+		assert(t, class != ResizeError && class != DecodeError)
+	}
+	assert(t, UnloadedPluginError.Contains(resizeErr))
+	assert(t, UnloadedPluginError.Contains(decodeErr))
+	assert(t, HostError.Contains(decodeErr))
+	assert(t, GetMessage(resizeErr) == "Resize Error: too big")
+	// IsAny agrees with Contains about tombstoned classes.
+	assert(t, IsAny(resizeErr, UnloadedPluginError))
+	assert(t, IsAny(resizeErr, ResizeError) && IsAny(decodeErr, DecodeError))
+	assert(t, IsAny(decodeErr, HostError))
+	assert(t, MatchingClass(resizeErr, HostError, UnloadedPluginError) ==
+		UnloadedPluginError)
+
+	reloaded, err := LoadPlugin("thumbnailer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Unload()
+	NewResizeError := reloaded.Root().NewClass("Resize Error")
+	assert(t, LookupClass(path) == NewResizeError)
+	assert(t, !NewResizeError.Contains(resizeErr))
+}
+
+func TestPluginUnloadKeepsIdentity(t *testing.T) {
+	first, err := LoadPlugin(unique("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadPlugin(unique("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ParseError := first.Root().NewClass("Parse Error")
+	SyntaxError := ParseError.NewClass("Syntax Error")
+	FetchError := second.Root().NewClass("Fetch Error")
+	parseErr, syntaxErr := ParseError.New("bad"), SyntaxError.New("worse")
+	fetchErr := FetchError.New("gone")
+	first.Unload()
+	second.Unload()
+
+	for _, err := range []error{parseErr, syntaxErr, fetchErr} {
+		assert(t, UnloadedPluginError.Contains(err))
+		assert(t, IsAny(err, UnloadedPluginError))
+	}
+	assert(t, !ParseError.Is(FetchError) && !FetchError.Is(ParseError))
+	assert(t, !FetchError.Contains(parseErr) && !ParseError.Contains(fetchErr))
+	assert(t, !IsAny(fetchErr, ParseError) && !IsAny(parseErr, FetchError))
+	assert(t, first.Root().Contains(syntaxErr) && ParseError.Contains(syntaxErr))
+	assert(t, !SyntaxError.Contains(parseErr))
+	assert(t, IsAny(syntaxErr, ParseError) && !IsAny(parseErr, SyntaxError))
+	assert(t, ClassID(ParseError) != ClassID(FetchError))
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	BusError := NewClass("Bus Error")
+	topicKey := GenSym()
+	ExportDataKey("topic", topicKey)
+	err := BusError.NewWith("queue full", SetData(topicKey, "jobs"))
+
+	buf, eerr := EncodeBinary(err)
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	view, eerr := ParseBinary(buf)
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	assert(t, view.ClassID() == ClassID(BusError))
+	assert(t, string(view.Path()) == BusError.Path())
+	assert(t, string(view.Message()) == "queue full")
+	assert(t, string(view.Stack()) == GetStack(err))
+	assert(t, &view.Message()[0] == &buf[bytes.Index(buf, []byte("queue full"))])
+
+	rehydrated, eerr := view.Rehydrate()
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	assert(t, BusError.Contains(rehydrated))
+	assert(t, GetData(rehydrated, topicKey) == "jobs")
+
+	assert(t, view.Exits() == nil)
+
+	_, eerr = ParseBinary(buf[:20])
+	assert(t, BinaryFormatError.Contains(eerr))
+	_, eerr = ParseBinary([]byte("nope"))
+	assert(t, BinaryFormatError.Contains(eerr))
+	_, eerr = ParseBinary(buf[:len(buf)-8])
+	assert(t, BinaryFormatError.Contains(eerr))
+
+	// an Error laid out by the FlatBuffers library's builder, as other
+	// languages' writers do.
+	other, _ := hex.DecodeString("18000000534d45421000140010000c0008000000000004001000000034000000" +
+		"2000000008000000393000000f0000004572726f722f427573204572726f7200" +
+		"0a00000071756575652066756c6c0000100000007b22746f706963223a226a6f" +
+		"6273227d00000000")
+	view, eerr = ParseBinary(other)
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	assert(t, view.ClassID() == 12345 && view.Stack() == nil)
+	assert(t, string(view.Path()) == BusError.Path())
+	assert(t, string(view.Message()) == "queue full")
+	assert(t, string(view.Data()) == `{"topic":"jobs"}`)
+}
+
+//go:noinline
+func stackDiffThrow() error {
+	return HierarchicalError.New("thrown")
+}
+
+//go:noinline
+func stackDiffCatch(err error) StackDiff {
+	return DiffStack(err)
+}
+
+func TestDiffStack(t *testing.T) {
+	needStacks(t)
+	diff := stackDiffCatch(stackDiffThrow())
+	assert(t, len(diff.Thrown) == 2 && strings.Contains(diff.Thrown[0], "stackDiffThrow"))
+	assert(t, len(diff.Caught) == 2 && strings.Contains(diff.Caught[0], "stackDiffCatch"))
+	assert(t, strings.HasSuffix(diff.Pivot, ".TestDiffStack"))
+	assert(t, len(diff.Common) > 0 && strings.Contains(diff.Common[0], "tRunner"))
+	assert(t, diff.String() == "thrown at "+diff.Thrown[0]+".."+diff.Pivot+
+		", caught at "+diff.Caught[0]+".."+diff.Pivot)
+	assert(t, strings.Contains(diff.Detail(), "both from:"))
+	assert(t, DiffStack(fmt.Errorf("plain")).String() == "thrown at unknown, caught at unknown")
+}
+
+func TestJournalHashChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "errors.log")
+	AuditError := NewClass("Audit Error")
+
+	j, err := OpenJournal(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, j.EnableHashChain() == nil)
+	assert(t, j.Write(AuditError.New("first")) == nil)
+	assert(t, j.Write(AuditError.New("second")) == nil)
+	assert(t, j.Close() == nil)
+
+	// reopening picks the chain back up.
+	j, err = OpenJournal(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, j.EnableHashChain() == nil)
+	assert(t, j.Write(AuditError.New("third")) == nil)
+	assert(t, j.Close() == nil)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := VerifyJournal(bytes.NewReader(data), "")
+	assert(t, err == nil && len(last) == 64)
+
+	lines := strings.SplitAfter(string(data), "\n")
+	tampered := strings.Replace(string(data), "second", "sec0nd", 1)
+	_, err = VerifyJournal(strings.NewReader(tampered), "")
+	assert(t, JournalChainError.Contains(err))
+	assert(t, strings.Contains(err.Error(), "line 2: hash mismatch"))
+
+	omitted := lines[0] + lines[2]
+	_, err = VerifyJournal(strings.NewReader(omitted), "")
+	assert(t, strings.Contains(err.Error(), "line 2: doesn't follow"))
+}
+
+func TestDefine(t *testing.T) {
+	type QuotaArgs struct{ Need, Have int }
+	LimitError := NewClass("Define Limit Error")
+	ErrQuota := Define[QuotaArgs](LimitError, "Quota Error",
+		"quota exceeded: need %d, have %d")
+	ErrMissing := Define[string](LimitError, "Missing Error", "no %q")
+
+	err := ErrQuota.New(QuotaArgs{Need: 5, Have: 3})
+	assert(t, GetMessage(err) == "Quota Error: quota exceeded: need 5, have 3")
+	assert(t, ErrQuota.Is(err) && LimitError.Contains(err))
+	assert(t, ErrQuota.Is(ProgrammerError.Wrap(err)))
+	assert(t, !ErrMissing.Is(err))
+	args, ok := ErrQuota.Fields(err)
+	assert(t, ok && args.Need == 5)
+
+	thrown := func() (err error) {
+		defer CatchPanic(&err)
+		ErrMissing.Throw("widget")
+		return nil
+	}()
+	assert(t, ErrMissing.Is(thrown))
+	assert(t, strings.Contains(GetMessage(thrown), `Missing Error: no "widget"`))
+
+	// unexported fields would silently shift the arguments.
+	type partialArgs struct {
+		Need int
+		note string
+	}
+	defined := func() (err error) {
+		defer CatchPanic(&err)
+		Define[partialArgs](LimitError, "Partial Error", "need %d (%s)")
+		return nil
+	}()
+	assert(t, ProgrammerError.Contains(defined, IncludeWrapped))
+	assert(t, strings.Contains(GetMessage(defined), "field note is unexported"))
+}
+
+func TestSampleData(t *testing.T) {
+	PayloadError := NewClass("Payload Error")
+	dumpKey := GenSym()
+	payload := []byte(strings.Repeat("x", 4096))
+
+	rolls := []float64{0.1, 0.9}
+	defer func(old func() float64) { sampleFloat = old }(sampleFloat)
+	sampleFloat = func() float64 {
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return roll
+	}
+	SampleData(dumpKey, 0.25)
+	defer SampleData(dumpKey, 1)
+
+	kept := PayloadError.NewWith("kept", SetData(dumpKey, payload))
+	dropped := PayloadError.NewWith("dropped", SetData(dumpKey, payload))
+	assert(t, bytes.Equal(GetData(kept, dumpKey).([]byte), payload))
+	sample, ok := GetData(dropped, dumpKey).(DataSample)
+	assert(t, ok && sample.Size == 4096 && len(sample.Hash) == 64)
+
+	SampleData(dumpKey, 1)
+	whole := PayloadError.NewWith("whole", SetData(dumpKey, payload))
+	assert(t, bytes.Equal(GetData(whole, dumpKey).([]byte), payload))
+}
+
+// foreignClassType and foreignErr mimic the types of another copy of this
+// package.
+type foreignClassType struct {
+	name   string
+	parent *foreignClassType
+}
+
+func (c *foreignClassType) String() string            { return c.name }
+func (c *foreignClassType) Parent() *foreignClassType { return c.parent }
+
+type foreignErr struct{ class *foreignClassType }
+
+func (e *foreignErr) Error() string            { return e.class.name + ": foreign" }
+func (e *foreignErr) Class() *foreignClassType { return e.class }
+
+func TestForeignCopiesMatchByPath(t *testing.T) {
+	CompatError := NewClass("Compat Error")
+	CompatLeafError := CompatError.NewClass("Compat Leaf Error")
+
+	root := &foreignClassType{name: "Error"}
+	leaf := &foreignClassType{name: "Compat Leaf Error",
+		parent: &foreignClassType{name: "Compat Error", parent: root}}
+	err := &foreignErr{class: leaf}
+	assert(t, GetClass(err) == CompatLeafError)
+	assert(t, CompatError.Contains(err))
+
+	unknown := &foreignErr{class: &foreignClassType{name: "Unregistered", parent: root}}
+	assert(t, !CompatError.Contains(unknown))
+	assert(t, GetClass(unknown) == SystemError)
+}
+
+func TestComponent(t *testing.T) {
+	DiskError := NewClass("Component Disk Error", Component("storage"))
+	get := func(fail error) (err error) {
+		defer TagComponent("cache", &err)
+		return fail
+	}
+
+	assert(t, GetComponent(get(DiskError.New("full"))) == "storage")
+	assert(t, GetComponent(get(HierarchicalError.New("miss"))) == "cache")
+	wrapped := ProgrammerError.Wrap(DiskError.New("full"), Component("api"))
+	assert(t, GetComponent(wrapped) == "storage")
+	assert(t, GetComponent(get(nil)) == "")
+	assert(t, GetComponent(get(fmt.Errorf("plain"))) == "")
+}
+
+func TestAdaptiveStacks(t *testing.T) {
+	needStacks(t)
+	defer func(old int) { Config.Adaptivestacks = old }(Config.Adaptivestacks)
+	Config.Adaptivestacks = 2
+	NoisyError := NewClass("Adaptive Noisy Error")
+
+	var stacks []int
+	for i := 0; i < 4; i++ {
+		stacks = append(stacks, len(NoisyError.New("noise").(*Error).stack))
+	}
+	other := NoisyError.New("elsewhere").(*Error)
+	assert(t, stacks[0] > 0 && stacks[1] > 0)
+	assert(t, stacks[2] == 0 && stacks[3] == 0)
+	assert(t, len(other.stack) > 0)
+
+	Config.Adaptivestacks = 1
+	ErrTyped := Define[string](NewClass("Adaptive Typed Error"),
+		"Typed Error", "typed %s")
+	throw := func(site func()) (err *Error) {
+		defer func() { err = recover().(*Error) }()
+		site()
+		return nil
+	}
+	var thrown []*Error
+	for i := 0; i < 2; i++ {
+		thrown = append(thrown, throw(func() { ErrTyped.Throw("noise") }))
+	}
+	first := thrown[0]
+	elsewhere := throw(func() { ErrTyped.Throw("elsewhere") })
+	assert(t, len(first.stack) > 0 && len(thrown[1].stack) == 0)
+	assert(t, len(elsewhere.stack) > 0)
+	assert(t, strings.Contains(first.Stack(), "TestAdaptiveStacks"))
+	assert(t, !strings.Contains(
+		strings.SplitN(first.Stack(), "\n", 2)[0], "Throw"))
+
+	Config.Adaptivestacks = 0
+	assert(t, len(NoisyError.New("noise").(*Error).stack) > 0)
+}
+
+func TestDeferStackCapture(t *testing.T) {
+	needStacks(t)
+	HandledError := NewClass("Deferred Stack Error", DeferStackCapture())
+
+	handled := HandledError.New("discarded").(*Error)
+	assert(t, len(handled.stack) == 1 && handled.stackDeferred)
+
+	escaped := HandledError.New("logged").(*Error)
+	// the frames inside this package are left out, which here includes
+	// the test itself, so the escape stack starts in fmt.
+	text := fmt.Sprint(escaped)
+	lines := strings.Split(escaped.Stack(), "\n")
+	assert(t, strings.Contains(lines[0], "TestDeferStackCapture") &&
+		strings.HasSuffix(lines[0], "(created)"))
+	assert(t, strings.HasPrefix(lines[1], "fmt."))
+	assert(t, strings.Contains(text, escaped.Stack()))
+	assert(t, !escaped.stackDeferred)
+
+	// SymbolizeAsync captures the escape stack here, not on its worker.
+	queued := HandledError.New("queued").(*Error)
+	SymbolizeAsync(queued)
+	lines = strings.Split(queued.Stack(), "\n")
+	assert(t, len(lines) > 1 && strings.HasPrefix(lines[1], "testing."))
+}
+
+func TestNewCause(t *testing.T) {
+	ConfigError := NewClass("Config Error", NoCaptureStack())
+	ParseError := NewClass("Parse Error", NoCaptureStack())
+
+	_, cause := os.Open("/nonexistent/config.toml")
+	err := ConfigError.NewCause(cause, "loading %s", "config.toml")
+	assert(t, GetMessage(err) == "Config Error: loading config.toml: "+
+		cause.Error())
+	assert(t, RootCause(err) == syscall.ENOENT)
+	_, ok := FindType[*os.PathError](err)
+	assert(t, ok)
+
+	parse := ParseError.New("line 3")
+	err = ConfigError.NewCause(parse, "loading")
+	assert(t, GetMessage(err) == "Config Error: loading: Parse Error: line 3")
+	assert(t, ParseError.Contains(err, IncludeWrapped))
+	assert(t, GetMessage(ConfigError.NewCause(nil, "empty")) ==
+		"Config Error: empty")
+}
+
+type requestIDContextKey struct{}
+
+func TestFromContextKeys(t *testing.T) {
+	requestIDKey := GenSym()
+	keys := []ContextKey{{Context: requestIDContextKey{}, Data: requestIDKey}}
+	ctx := context.WithValue(context.Background(), requestIDContextKey{},
+		"req-42")
+	RequestError := NewClass("Request Error", NoCaptureStack())
+
+	err := RequestError.NewWith("failed", FromContextKeys(ctx, keys...))
+	assert(t, GetData(err, requestIDKey) == "req-42")
+	err = RequestError.NewWith("failed",
+		FromContextKeys(context.Background(), keys...))
+	assert(t, GetData(err, requestIDKey) == nil)
+
+	assert(t, GetData(RequestError.NewCtx(ctx, "failed"), requestIDKey) == nil)
+	RegisterContextKeys(keys...)
+	assert(t, GetData(RequestError.NewCtx(ctx, "failed"), requestIDKey) ==
+		"req-42")
+}
+
+func TestIsAny(t *testing.T) {
+	RouteError := NewClass("Route Error")
+	RetryRouteError := RouteError.NewClass("Retry Route Error")
+	DropRouteError := RouteError.NewClass("Drop Route Error")
+
+	err := RetryRouteError.New("flaky")
+	assert(t, IsAny(err, DropRouteError, RouteError))
+	assert(t, !IsAny(err, DropRouteError, SystemError))
+	assert(t, !IsAny(nil, RouteError) && !IsAny(err))
+	assert(t, MatchingClass(err, DropRouteError, RetryRouteError,
+		RouteError) == RetryRouteError)
+	assert(t, MatchingClass(io.EOF, RouteError, SystemError) == SystemError)
+	assert(t, MatchingClass(err, DropRouteError) == nil)
+
+	// interned namespaced classes match each other.
+	ns := unique("example.com/any")
+	first := NewClass("Any Shared", Namespace(ns))
+	second := NewClass("Any Shared", Namespace(ns))
+	assert(t, IsAny(second.New("x"), first) && IsAny(first.New("x"), second))
+}
+
+func TestMemoryCap(t *testing.T) {
+	needStacks(t)
+	defer func(old int) { Config.Memorycap = old }(Config.Memorycap)
+	Config.Memorycap = 1
+	StormError := NewClass("Storm Error")
+	payload := strings.Repeat("x", 1000)
+
+	first := StormError.NewWith("first", SetData(ExpectedKey, payload))
+	assert(t, len(first.(*Error).stack) > 0 && RetainedBytes() > 0)
+	degraded := DegradedCount()
+	second := StormError.NewWith("second", SetData(ExpectedKey, payload))
+	assert(t, len(second.(*Error).stack) == 0)
+	assert(t, len(GetData(second, ExpectedKey).(string)) < len(payload))
+	assert(t, DegradedCount() == degraded+1)
+
+	first, second = nil, nil
+	deadline := time.Now().Add(5 * time.Second)
+	for RetainedBytes() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("still retaining %d bytes", RetainedBytes())
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNilErrorClass(t *testing.T) {
+	var OptionalError *ErrorClass
+
+	err := OptionalError.New("unset %s", "class")
+	assert(t, GetClass(err) == HierarchicalError)
+	assert(t, GetMessage(err) == "Error: unset class")
+	assert(t, GetClass(OptionalError.Wrap(io.EOF)) == HierarchicalError)
+	assert(t, GetClass(OptionalError.NewCtx(context.Background(), "x")) ==
+		HierarchicalError)
+	assert(t, OptionalError.Wrap(nil) == nil)
+
+	assert(t, !OptionalError.Contains(err, IncludeWrapped))
+	assert(t, !OptionalError.Is(HierarchicalError))
+	assert(t, !HierarchicalError.Is(OptionalError))
+	assert(t, OptionalError.Innermost(err) == nil)
+	assert(t, OptionalError.Path() == "" && OptionalError.String() == "nil")
+	assert(t, OptionalError.Parent() == nil)
+	assert(t, OptionalError.GetData(ExpectedKey) == nil)
+
+	child := OptionalError.NewClass("Optional Child Error")
+	assert(t, child.Parent() == HierarchicalError)
+}
+
+func TestConflictClasses(t *testing.T) {
+	err := StaleVersionError.NewWith("updating profile", Versions(3, 4))
+	assert(t, ConflictError.Contains(err))
+	expected, actual, ok := GetVersions(err)
+	assert(t, ok && expected == 3 && actual == 4)
+	assert(t, GetPolicy(err).HTTPStatus == 412)
+	assert(t, GetPolicy(ConflictError.New("cas failed")).HTTPStatus == 409)
+	_, _, ok = GetVersions(ConflictError.New("cas failed"))
+	assert(t, !ok)
+
+	_, _, data, eerr := ExportABI(err)
+	assert(t, eerr == nil &&
+		string(data) == `{"actual_version":4,"expected_version":3}`)
+}
+
+func TestHasStack(t *testing.T) {
+	needStacks(t)
+	err := NewClass("stacked", CaptureStack()).New("boom")
+	assert(t, HasStack(err))
+	text := err.(*Error).StackText()
+	assert(t, text != "" && text == GetStack(err))
+
+	assert(t, !HasStack(NewClass("unstacked", NoCaptureStack()).New("boom")))
+	assert(t, !HasStack(io.EOF))
+	assert(t, HasStack(SystemError.ForTest("x", []string{"frame1"})))
+}
+
+func TestDeferredNew(t *testing.T) {
+	class := NewClass("deferred", NoCaptureStack())
+	calls := 0
+	err := class.DeferredNew(func() string {
+		calls++
+		return "expensive message"
+	})
+	assert(t, class.Contains(err) && calls == 0)
+	assert(t, GetMessage(err) == "deferred: expensive message" && calls == 1)
+	assert(t, err.Error() == "deferred: expensive message" && calls == 1)
+
+	scrubbers.mu.RLock()
+	registered := scrubbers.list
+	scrubbers.mu.RUnlock()
+	t.Cleanup(func() {
+		scrubbers.mu.Lock()
+		scrubbers.list = registered
+		scrubbers.mu.Unlock()
+	})
+	RegisterScrubber(func(m string) string {
+		return strings.Replace(m, "secret", "<redacted>", -1)
+	})
+	calls = 0
+	err = class.DeferredNew(func() string {
+		calls++
+		return "token secret"
+	})
+	assert(t, calls == 0)
+	assert(t, GetMessage(err) == "deferred: token <redacted>" && calls == 1)
+	original := stderrors.Unwrap(err.(*Error).err)
+	assert(t, original != nil && original.Error() == "token secret")
+}
+
+func TestStdlibUnwrap(t *testing.T) {
+	err := SystemError.NewClass("reading").Wrap(io.EOF)
+	err = HierarchicalError.Wrap(err, SetData(GenSym(), 1))
+	assert(t, stderrors.Is(err, io.EOF))
+	assert(t, !stderrors.Is(err, io.ErrUnexpectedEOF))
+	assert(t, stderrors.Unwrap(err) != nil)
+
+	_, perr := os.Open("/nonexistent/file")
+	err = NewClass("config").Wrap(perr)
+	var pathErr *os.PathError
+	assert(t, stderrors.As(err, &pathErr) && pathErr.Path == "/nonexistent/file")
+	assert(t, stderrors.Is(err, os.ErrNotExist))
+}
+
+func TestRaises(t *testing.T) {
+	fruit := NewClass("fruit")
+	apple := fruit.NewClass("apple")
+	other := NewClass("other")
+	pickID := unique("test.Pick")
+	Raises(pickID, fruit)
+	assert(t, len(DeclaredRaises(pickID)) == 1)
+	assert(t, !Undeclared(pickID, apple.New("bruised")))
+	assert(t, Undeclared(pickID, other.New("oops")))
+	assert(t, !Undeclared("test.Undeclared", other.New("oops")))
+
+	var reported []error
+	old := OnUndeclaredRaise
+	OnUndeclaredRaise = func(id interface{}, err error) {
+		reported = append(reported, err)
+	}
+	defer func() {
+		OnUndeclaredRaise = old
+		Config.Checkraises = false
+	}()
+	pick := func(err error) (rv error) {
+		defer CheckRaises(pickID, &rv)
+		return err
+	}
+	pick(other.New("oops"))
+	assert(t, len(reported) == 0)
+	Config.Checkraises = true
+	pick(apple.New("bruised"))
+	pick(nil)
+	pick(other.New("oops"))
+	assert(t, len(reported) == 1 && other.Contains(reported[0]))
+}
+
+func TestMigrateClasses(t *testing.T) {
+	legacy := NewClass("Legacy")
+	storage := NewClass("Storage")
+	db := legacy.NewClass("DB")
+	timeout := db.NewClass("Timeout")
+	err := timeout.New("slow")
+	oldID := ClassID(timeout)
+
+	Rename(db, "Database")
+	assert(t, timeout.Path() == "Error/Legacy/Database/Timeout")
+	Reparent(db, storage)
+	assert(t, timeout.Path() == "Error/Storage/Database/Timeout")
+	assert(t, LookupClass("Error/Legacy/DB/Timeout") == timeout)
+	assert(t, LookupClass("Error/Legacy/Database") == db)
+	assert(t, LookupClass("Error/Storage/Database/Timeout") == timeout)
+	assert(t, ClassForID(oldID) == timeout)
+	assert(t, ClassForID(ClassID(timeout)) == timeout)
+	assert(t, len(PathHistory(timeout)) == 2 &&
+		PathHistory(timeout)[0] == "Error/Legacy/DB/Timeout")
+
+	assert(t, storage.Contains(err) && !legacy.Contains(err))
+	assert(t, IsAny(err, storage) && !IsAny(err, legacy))
+
+	defer func() {
+		rec, _ := recover().(error)
+		assert(t, ProgrammerError.Contains(rec))
+	}()
+	Reparent(storage, timeout)
+}
+
+func TestClassIDCollision(t *testing.T) {
+	// "Error/Collision Error 652038" and "Error/Collision Error 1111642"
+	// hash to the same 32-bit FNV-1a.
+	first := NewClass("Collision Error 652038")
+	rec, _ := runtimePanic(func() {
+		NewClass("Collision Error 1111642")
+	}).(error)
+	assert(t, ProgrammerError.Contains(rec))
+	assert(t, ClassForID(ClassID(first)) == first)
+	assert(t, LookupClass("Error/Collision Error 1111642") == nil)
+
+	moved := NewClass("Collision Error")
+	rec, _ = runtimePanic(func() {
+		Rename(moved, "Collision Error 1111642")
+	}).(error)
+	assert(t, ProgrammerError.Contains(rec))
+	assert(t, moved.Path() == "Error/Collision Error")
+	assert(t, LookupClass("Error/Collision Error") == moved)
+
+	ids := make(map[uint32]string)
+	for _, class := range Classes() {
+		other, taken := ids[ClassID(class)]
+		assert(t, !taken || other == class.Path())
+		ids[ClassID(class)] = class.Path()
+	}
+}
+
+func TestStackFrames(t *testing.T) {
+	needStacks(t)
+	err := NewClass("framed", CaptureStack()).New("boom")
+	frames := GetStackFrames(err)
+	assert(t, len(frames) > 0)
+	assert(t, strings.HasSuffix(frames[0].Function, ".TestStackFrames"))
+	assert(t, filepath.Base(frames[0].File) == "errors_test.go")
+
+	deferred := NewClass("framed lazily", CaptureStack(), DeferStackCapture())
+	frames = GetStackFrames(deferred.New("boom"))
+	assert(t, len(frames) > 1 &&
+		strings.HasSuffix(frames[0].Function, ".TestStackFrames"))
+
+	assert(t, GetStackFrames(SystemError.ForTest("x", []string{"a"})) == nil)
+	assert(t, GetStackFrames(io.EOF) == nil)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	needStacks(t)
+	class := NewClass("json", CaptureStack())
+	key := GenSym()
+	ExportDataKey("json_test_retries", key)
+	err := class.NewWith("lost", SetData(key, 3))
+
+	data, merr := json.Marshal(map[string]interface{}{"error": err})
+	assert(t, merr == nil)
+	var resp struct {
+		Error *Error `json:"error"`
+	}
+	assert(t, json.Unmarshal(data, &resp) == nil)
+	back := resp.Error
+	assert(t, class.Contains(back))
+	assert(t, GetMessage(back) == GetMessage(err))
+	assert(t, GetData(back, key) == 3.0)
+	assert(t, HasStack(back) && GetStack(back) == GetStack(err))
+
+	data, merr = MarshalError(io.EOF)
+	assert(t, merr == nil)
+	plain, uerr := UnmarshalError(data)
+	assert(t, uerr == nil && GetClass(plain) == GetClass(io.EOF) &&
+		WrappedErr(plain).Error() == "EOF")
+
+	unknown, uerr := UnmarshalError([]byte(`{"class":"Error/Nope","message":"x"}`))
+	assert(t, uerr == nil && GetClass(unknown) == HierarchicalError)
+	none, uerr := UnmarshalError([]byte("null"))
+	assert(t, uerr == nil && none == nil)
+}
+
+func TestRegistryScope(t *testing.T) {
+	scope := NewScope()
+	ref := func() weak.Pointer[ErrorClass] {
+		class := scope.NewClass(nil, "scoped")
+		child := class.NewClass("child")
+		assert(t, LookupClass("Error/scoped") == class)
+		assert(t, LookupClass("Error/scoped/child") == child)
+		return weak.Make(child)
+	}()
+	FreezeRegistry()
+	func() {
+		defer atomic.StoreUint32(&frozen, 0)
+		scope.NewClass(nil, "after freeze")
+	}()
+
+	scope.Close()
+	scope.Close()
+	assert(t, LookupClass("Error/scoped") == nil)
+	assert(t, LookupClass("Error/scoped/child") == nil)
+	for _, class := range Classes() {
+		assert(t, !scope.owns(class))
+	}
+	runtime.GC()
+	assert(t, ref.Value() == nil)
+
+	defer func() {
+		rec, _ := recover().(error)
+		assert(t, ProgrammerError.Contains(rec))
+	}()
+	scope.NewClass(nil, "closed")
+}
+
+func TestProgrammaticConfig(t *testing.T) {
+	needStacks(t)
+	defer SetStackCaptureSize(Config.Stackcapturesize)
+	class := NewClass("configured", CaptureStack())
+	SetStackCaptureSize(2)
+	assert(t, len(GetStackFrames(class.New("shallow"))) <= 2)
+	SetStackCaptureSize(512)
+	assert(t, len(GetStackFrames(class.New("deep"))) > 2)
+
+	var logged []string
+	SetLogger(func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	defer SetLogger(nil)
+	LogMethod("hello %d", 1)
+	assert(t, len(logged) == 1 && logged[0] == "hello 1")
+}
+
+func TestClassBitsReused(t *testing.T) {
+	scope := NewScope()
+	bit := func() int {
+		return scope.NewClass(nil, "Short Lived Error").bit
+	}()
+	scope.Close()
+
+	reclaimed := func() bool {
+		classBits.Lock()
+		defer classBits.Unlock()
+		for _, free := range classBits.free {
+			if free == bit {
+				return true
+			}
+		}
+		return false
+	}
+	for deadline := time.Now().Add(5 * time.Second); !reclaimed(); {
+		if time.Now().After(deadline) {
+			t.Fatal("bit of collected class never reclaimed")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	next := NewClass("Reusing Error")
+	assert(t, next.bit <= bit)
+	assert(t, !IsAny(next.New("x"), HierarchicalError.NewClass("Other Error")))
+}
+
+func TestClassOverrides(t *testing.T) {
+	needStacks(t)
+	TenantError := NewClass("Tenant Error", NoCaptureStack())
+	QuotaError := TenantError.NewClass("Tenant Quota Error")
+	tenantKey := GenSym()
+
+	ctx := context.Background()
+	assert(t, len(QuotaError.NewCtx(ctx, "over").(*Error).stack) == 0)
+
+	debug := WithClassOverrides(ctx, TenantError, CaptureStack(),
+		SetData(tenantKey, "debug"))
+	err := QuotaError.NewCtx(debug, "over").(*Error)
+	assert(t, len(err.stack) > 0)
+	assert(t, GetData(err, tenantKey) == "debug")
+	assert(t, GetData(HierarchicalError.NewCtx(debug, "x"), tenantKey) == nil)
+
+	inner := WithClassOverrides(debug, QuotaError, SetData(tenantKey, "inner"))
+	assert(t, GetData(QuotaError.NewCtx(inner, "over"), tenantKey) == "inner")
+	wrapped := QuotaError.WrapCtx(inner, io.EOF, SetData(tenantKey, "explicit"))
+	assert(t, GetData(wrapped, tenantKey) == "explicit")
+	assert(t, len(QuotaError.New("over").(*Error).stack) == 0)
+}
+
+func TestCommonClass(t *testing.T) {
+	FruitError := NewClass("Common Fruit Error")
+	AppleError := FruitError.NewClass("Common Apple Error")
+	FujiError := AppleError.NewClass("Common Fuji Error")
+	GrapeError := FruitError.NewClass("Common Grape Error")
+
+	assert(t, CommonClass() == nil)
+	assert(t, CommonClass(nil, nil) == nil)
+	assert(t, CommonClass(FujiError.New("a")) == FujiError)
+	assert(t, CommonClass(FujiError.New("a"), nil, AppleError.New("b")) == AppleError)
+	assert(t, CommonClass(FujiError.New("a"), GrapeError.New("b")) == FruitError)
+	assert(t, CommonClass(FujiError.New("a"), io.EOF) == nil)
+	assert(t, CommonClass(io.EOF, io.ErrUnexpectedEOF) == IOError)
+}
+
+func TestReclass(t *testing.T) {
+	VendorError := NewClass("Reclass Vendor Error")
+	TransientError := NewClass("Reclass Transient Error",
+		SetPolicy(Policy{Retryable: PolicyYes}))
+	key := GenSym()
+
+	err := VendorError.NewWith("flaky", SetData(key, "kept"))
+	id := GetID(err)
+	reclassed := Reclass(err, TransientError)
+	assert(t, GetClass(reclassed) == TransientError)
+	assert(t, GetClass(err) == VendorError)
+	assert(t, WrappedErr(reclassed) == WrappedErr(err))
+	assert(t, GetStack(reclassed) == GetStack(err))
+	assert(t, GetID(reclassed) == id)
+	assert(t, GetData(reclassed, key) == "kept")
+	assert(t, Retryable(reclassed) && !Retryable(err))
+	assert(t, GetOriginalClass(reclassed) == VendorError)
+	assert(t, GetOriginalClass(err) == nil)
+	assert(t, GetOriginalClass(Reclass(reclassed, ProgrammerError)) == VendorError)
+
+	// errors whose ID hasn't been asked for yet share it with their reclass.
+	fresh := VendorError.New("fresh")
+	reclassed = Reclass(fresh, TransientError)
+	assert(t, GetID(reclassed) != "" && GetID(reclassed) == GetID(fresh))
+
+	foreign := Reclass(io.ErrUnexpectedEOF, TransientError)
+	assert(t, GetClass(foreign) == TransientError)
+	assert(t, WrappedErr(foreign) == io.ErrUnexpectedEOF)
+	assert(t, GetOriginalClass(foreign) == UnexpectedEOFError)
+	assert(t, Reclass(nil, TransientError) == nil)
+}
+
+func TestWrapAllReturns(t *testing.T) {
+	StorageError := NewClass("Decorated Storage Error")
+	get := func(key string, fail ...bool) ([]byte, error) {
+		if len(fail) > 0 && fail[0] {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return []byte(key), nil
+	}
+
+	wrapped := WrapAllReturns(StorageError, get)
+	val, err := wrapped("k")
+	assert(t, err == nil && string(val) == "k")
+	_, err = wrapped("k", true)
+	assert(t, StorageError.Contains(err) && WrappedErr(err) == io.ErrUnexpectedEOF)
+
+	noop := func() error { return nil }
+	assert(t, WrapAllReturns(StorageError, noop)() == nil)
+	assert(t, StorageError.Contains(WrapFunc(StorageError, func() error {
+		return io.EOF
+	})()))
+	assert(t, StorageError.Contains(WrapFunc1(StorageError, func(string) error {
+		return io.EOF
+	})("k")))
+	n, err := WrapFunc1R(StorageError, func(n int) (int, error) {
+		return n, io.EOF
+	})(3)
+	assert(t, n == 3 && StorageError.Contains(err))
+
+	defer func() {
+		assert(t, ProgrammerError.Contains(recover().(error)))
+	}()
+	WrapAllReturns(StorageError, func() int { return 0 })
+}
+
+func TestClone(t *testing.T) {
+	key := GenSym()
+	err := HierarchicalError.NewWith("shared", SetData(key, "original"))
+	clone := Clone(err)
+	assert(t, clone != err.(*Error))
+	assert(t, GetID(clone) == GetID(err))
+	assert(t, GetStack(clone) == GetStack(err))
+
+	AttachData(clone, key, "annotated")
+	Record(clone)
+	assert(t, GetData(err, key) == "original")
+	assert(t, GetData(clone, key) == "annotated")
+	assert(t, GetExits(err) == "")
+	assert(t, GetExits(clone) != "")
+
+	assert(t, Clone(io.EOF) == nil)
+	assert(t, Clone(nil) == nil)
+}
+
+type countingBudget struct {
+	created, caught map[string]int
+}
+
+func (b *countingBudget) Created(request string, err error) { b.created[request]++ }
+func (b *countingBudget) Caught(request string, err error)  { b.caught[request]++ }
+
+func TestErrorBudget(t *testing.T) {
+	type requestKey struct{}
+	b := &countingBudget{created: map[string]int{}, caught: map[string]int{}}
+	SetErrorBudget(b, func(ctx context.Context) string {
+		request, _ := ctx.Value(requestKey{}).(string)
+		return request
+	})
+	defer SetErrorBudget(nil, nil)
+
+	ctx := context.WithValue(context.Background(), requestKey{}, "r1")
+	err := HierarchicalError.NewCtx(ctx, "soft failure")
+	HierarchicalError.WrapCtx(ctx, io.EOF)
+	HierarchicalError.NewCtx(context.Background(), "anonymous")
+	HierarchicalError.New("no context")
+	CaughtCtx(ctx, err, "")
+	Caught(err)
+
+	assert(t, b.created["r1"] == 2 && len(b.created) == 1)
+	assert(t, b.caught["r1"] == 1 && len(b.caught) == 1)
+
+	rec, _ := runtimePanic(func() { SetErrorBudget(b, nil) }).(error)
+	assert(t, ProgrammerError.Contains(rec))
+	HierarchicalError.NewCtx(ctx, "still accounted")
+	assert(t, b.created["r1"] == 3)
+}
+
+func TestHint(t *testing.T) {
+	QuotaError := NewClass("Hinted Quota Error", SetHint("raise the quota"))
+	err := QuotaError.New("full")
+	assert(t, GetHint(err) == "raise the quota")
+	assert(t, GetHint(QuotaError.NewWith("full", SetHint("wait a minute"))) ==
+		"wait a minute")
+	assert(t, GetHint(HierarchicalError.Wrap(err)) == "raise the quota")
+	assert(t, GetHint(HierarchicalError.Wrap(err, SetHint("outer"))) == "outer")
+	assert(t, GetHint(io.EOF) == "" && GetHint(nil) == "")
+}
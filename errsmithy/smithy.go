@@ -0,0 +1,126 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errsmithy classifies the API errors returned by clients generated
+with smithy-go, such as aws-sdk-go-v2, so GetClass, Contains and try plans
+work with them:
+
+	func init() { errsmithy.Register() }
+
+Errors are recognized by the methods of smithy.APIError (ErrorCode,
+ErrorMessage and ErrorFault), so this package doesn't depend on smithy-go.
+*/
+package errsmithy
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	// APIError is the parent of every smithy API error class.
+	APIError = errors.SystemError.NewClass("Smithy API Error")
+	// ClientFaultError and ServerFaultError are API errors the service
+	// blamed on the caller or itself.
+	ClientFaultError = APIError.NewClass("Smithy Client Fault Error",
+		errors.SetPolicy(errors.Policy{HTTPStatus: http.StatusBadRequest}))
+	ServerFaultError = APIError.NewClass("Smithy Server Fault Error",
+		errors.SetPolicy(errors.Policy{Retryable: errors.PolicyYes,
+			HTTPStatus: http.StatusBadGateway}))
+	// ThrottlingError is an API error whose code says the caller was
+	// throttled, whatever the fault.
+	ThrottlingError = APIError.NewClass("Smithy Throttling Error",
+		errors.SetPolicy(errors.Policy{Retryable: errors.PolicyYes,
+			HTTPStatus: http.StatusTooManyRequests}))
+
+	// ThrottlingCodes are the error codes classified as ThrottlingError.
+	ThrottlingCodes = map[string]bool{
+		"Throttling":                             true,
+		"ThrottlingException":                    true,
+		"ThrottledException":                     true,
+		"RequestThrottledException":              true,
+		"TooManyRequestsException":               true,
+		"ProvisionedThroughputExceededException": true,
+		"TransactionInProgressException":         true,
+		"RequestLimitExceeded":                   true,
+		"BandwidthLimitExceeded":                 true,
+		"LimitExceededException":                 true,
+		"RequestThrottled":                       true,
+		"SlowDown":                               true,
+		"PriorRequestNotComplete":                true,
+		"EC2ThrottledException":                  true,
+	}
+)
+
+// smithy.ErrorFault values.
+const (
+	faultServer = 1
+	faultClient = 2
+)
+
+type apiError interface {
+	ErrorCode() string
+	ErrorMessage() string
+}
+
+// Register adds Classify to the errors package's classifiers.
+func Register() {
+	errors.RegisterClassifier(Classify)
+}
+
+// Classify returns the class for a smithy API error (or an error wrapping
+// one), or nil if err isn't one.
+func Classify(err error) *errors.ErrorClass {
+	for ; err != nil; err = unwrap(err) {
+		api, ok := err.(apiError)
+		if !ok {
+			continue
+		}
+		if ThrottlingCodes[api.ErrorCode()] {
+			return ThrottlingError
+		}
+		switch fault(err) {
+		case faultServer:
+			return ServerFaultError
+		case faultClient:
+			return ClientFaultError
+		}
+		return APIError
+	}
+	return nil
+}
+
+// fault calls err's ErrorFault method, whose result type is smithy's.
+func fault(err error) int64 {
+	method := reflect.ValueOf(err).MethodByName("ErrorFault")
+	if !method.IsValid() || method.Type().NumIn() != 0 ||
+		method.Type().NumOut() != 1 {
+		return 0
+	}
+	switch result := method.Call(nil)[0]; result.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return result.Int()
+	}
+	return 0
+}
+
+func unwrap(err error) error {
+	if wrapper, ok := err.(interface{ Unwrap() error }); ok {
+		return wrapper.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errsmithy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// fakeFault has the shape of smithy.ErrorFault.
+type fakeFault int
+
+type fakeAPIError struct {
+	code  string
+	fault fakeFault
+}
+
+func (e *fakeAPIError) Error() string         { return "api error " + e.code }
+func (e *fakeAPIError) ErrorCode() string     { return e.code }
+func (e *fakeAPIError) ErrorMessage() string  { return "message" }
+func (e *fakeAPIError) ErrorFault() fakeFault { return e.fault }
+
+func TestClassify(t *testing.T) {
+	Register()
+	cases := []struct {
+		err   error
+		class *errors.ErrorClass
+	}{
+		{&fakeAPIError{"NoSuchKey", faultClient}, ClientFaultError},
+		{&fakeAPIError{"InternalError", faultServer}, ServerFaultError},
+		{&fakeAPIError{"SlowDown", faultServer}, ThrottlingError},
+		{&fakeAPIError{"Mystery", 0}, APIError},
+		{fmt.Errorf("put: %w", &fakeAPIError{"NoSuchKey", faultClient}), ClientFaultError},
+	}
+	for _, c := range cases {
+		if class := errors.GetClass(c.err); class != c.class {
+			t.Errorf("%v classified as %v, want %v", c.err, class, c.class)
+		}
+	}
+	if !errors.Retryable(&fakeAPIError{"SlowDown", faultClient}) {
+		t.Error("throttling not retryable")
+	}
+	if class := Classify(fmt.Errorf("plain")); class != nil {
+		t.Errorf("plain error classified as %v", class)
+	}
+}
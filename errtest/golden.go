@@ -0,0 +1,201 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errtest provides helpers for testing code that constructs errors.
+
+Golden compares an error against a golden file, so regression tests of error
+construction logic (which class, which message, which data) stay short and
+stable:
+
+	func TestLoadConfig(t *testing.T) {
+		_, err := LoadConfig("testdata/bad.conf")
+		errtest.Golden(t, err, "testdata/bad_conf.golden")
+	}
+
+Run the tests with ERRTEST_UPDATE=1 in the environment to write or refresh
+the golden files. (errtest registers no flags, so it doesn't clash with the
+flags of the tests importing it.)
+*/
+package errtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// updateEnv is the environment variable that has Golden write golden files.
+const updateEnv = "ERRTEST_UPDATE"
+
+// Render formats err deterministically for golden files: one block per
+// layer, outermost first, giving the layer's class path, its own message and
+// the type of the error it wraps (unless it just wraps another layer), and
+// the data stored under keys named with errors.ExportDataKey.
+// Stacks, exits, IDs and times vary from run to run, so they are left out.
+func Render(err error) string {
+	var blocks []string
+	for _, layer := range layers(err) {
+		blocks = append(blocks, strings.Join(layer, "\n"))
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// layers renders each layer of err as "field: value" lines.
+func layers(err error) (rv [][]string) {
+	if err == nil {
+		return [][]string{{"nil"}}
+	}
+	for err != nil {
+		cast, ok := err.(*errors.Error)
+		if !ok {
+			return append(rv, []string{
+				fmt.Sprintf("type: %T", err),
+				"message: " + err.Error()})
+		}
+		layer := []string{"class: " + cast.Class().Path()}
+		wrapped := cast.WrappedErr()
+		if _, ok := wrapped.(*errors.Error); !ok {
+			layer = append(layer, "message: "+wrapped.Error(),
+				fmt.Sprintf("wraps: %T", wrapped))
+		}
+		layer = append(layer, dataLines(cast)...)
+		rv = append(rv, layer)
+		if _, ok := wrapped.(*errors.Error); !ok {
+			return rv
+		}
+		err = wrapped
+	}
+	return rv
+}
+
+// dataLines renders the layer's named data, sorted by name.
+func dataLines(layer *errors.Error) []string {
+	data, _, err := errors.EncodeBounded(layer, math.MaxInt)
+	if err != nil {
+		return []string{"data: " + err.Error()}
+	}
+	var encoded struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return []string{"data: " + err.Error()}
+	}
+	values := encoded.Data
+	if len(values) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("data %s: %s", name, values[name]))
+	}
+	return lines
+}
+
+// Golden fails t unless err renders (see Render) the same as the golden file
+// at path, reporting each layer's differing fields. With ERRTEST_UPDATE set
+// to anything but the empty string, it writes the golden file instead.
+func Golden(t testing.TB, err error, path string) {
+	t.Helper()
+	got := Render(err)
+	if os.Getenv(updateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", readErr, updateEnv)
+	}
+	if diffs := diff(parse(got), parse(string(want))); len(diffs) > 0 {
+		t.Errorf("error doesn't match %s (run with %s=1 to accept):\n%s",
+			path, updateEnv, strings.Join(diffs, "\n"))
+	}
+}
+
+// parse splits a rendering back into layers of lines.
+func parse(rendered string) (rv [][]string) {
+	for _, block := range strings.Split(strings.TrimSpace(rendered), "\n\n") {
+		rv = append(rv, strings.Split(block, "\n"))
+	}
+	return rv
+}
+
+// diff compares renderings field by field.
+func diff(got, want [][]string) (diffs []string) {
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, fmt.Sprintf("layer %d: missing, want %s",
+				i, strings.Join(want[i], "; ")))
+			continue
+		case i >= len(want):
+			diffs = append(diffs, fmt.Sprintf("layer %d: unexpected %s",
+				i, strings.Join(got[i], "; ")))
+			continue
+		}
+		gotFields, wantFields := fields(got[i]), fields(want[i])
+		names := make([]string, 0, len(gotFields)+len(wantFields))
+		for name := range gotFields {
+			names = append(names, name)
+		}
+		for name := range wantFields {
+			if _, ok := gotFields[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			g, gok := gotFields[name]
+			w, wok := wantFields[name]
+			switch {
+			case !gok:
+				diffs = append(diffs, fmt.Sprintf("layer %d %s: missing, want %s",
+					i, name, w))
+			case !wok:
+				diffs = append(diffs, fmt.Sprintf("layer %d %s: unexpected %s",
+					i, name, g))
+			case g != w:
+				diffs = append(diffs, fmt.Sprintf("layer %d %s: got %s, want %s",
+					i, name, g, w))
+			}
+		}
+	}
+	return diffs
+}
+
+func fields(lines []string) map[string]string {
+	rv := make(map[string]string, len(lines))
+	for _, line := range lines {
+		name, value, _ := strings.Cut(line, ": ")
+		rv[name] = value
+	}
+	return rv
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errtest
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	FruitError   = errors.NewClass("Fruit Error")
+	AppleError   = FruitError.NewClass("Apple Error")
+	OrchardError = errors.NewClass("Orchard Error")
+	RequestKey   = errors.GenSym()
+)
+
+func init() {
+	errors.ExportDataKey("errtest.request", RequestKey)
+}
+
+func bruised() error {
+	return AppleError.Wrap(io.ErrUnexpectedEOF,
+		errors.SetData(RequestKey, map[string]int{"b": 2, "a": 1}))
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, bruised(), "testdata/bruised.golden")
+	Golden(t, OrchardError.Wrap(bruised()), "testdata/orchard.golden")
+	Golden(t, nil, "testdata/nil.golden")
+}
+
+func TestGoldenLeavesErrorAlone(t *testing.T) {
+	errors.Config.Journeys = true
+	defer func() { errors.Config.Journeys = false }()
+	err := bruised()
+	before := len(errors.Journey(err))
+	Golden(t, err, "testdata/bruised.golden")
+	if after := len(errors.Journey(err)); after != before {
+		t.Fatalf("journey grew from %d to %d events", before, after)
+	}
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new", "bruised.golden")
+	t.Setenv(updateEnv, "1")
+	Golden(t, bruised(), path)
+	t.Setenv(updateEnv, "")
+	Golden(t, bruised(), path)
+}
+
+func TestDiff(t *testing.T) {
+	got := parse(Render(bruised()))
+	want := parse(Render(AppleError.New("bruised")))
+	diffs := diff(got, want)
+	expected := []string{
+		`layer 0 data errtest.request: unexpected {"a":1,"b":2}`,
+		`layer 0 message: got unexpected EOF, want bruised`,
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Fatalf("diffs %q", diffs)
+	}
+	if diffs := diff(append(got, got...), got); len(diffs) != 1 {
+		t.Fatalf("diffs %q", diffs)
+	}
+}
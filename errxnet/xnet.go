@@ -0,0 +1,102 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package errxnet classifies the HTTP/2 errors of golang.org/x/net/http2 (and
+the copy of it bundled into net/http), so GetClass, Contains and try plans
+can tell a refused stream or a GOAWAY from other network failures:
+
+	func init() { errxnet.Register() }
+
+Errors are recognized by type name, so this package doesn't depend on
+golang.org/x/net.
+*/
+package errxnet
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	// HTTP2Error is the parent of every HTTP/2 error class.
+	HTTP2Error = errors.NetworkError.NewClass("HTTP2 Error")
+	// StreamError is an error on a single stream (http2.StreamError).
+	StreamError = HTTP2Error.NewClass("HTTP2 Stream Error")
+	// RefusedStreamError is a stream the server refused before processing
+	// it, which is always safe to retry.
+	RefusedStreamError = StreamError.NewClass("HTTP2 Refused Stream Error",
+		errors.SetPolicy(errors.Policy{Retryable: errors.PolicyYes}))
+	// GoAwayError is a connection the server is shutting down
+	// (http2.GoAwayError).
+	GoAwayError = HTTP2Error.NewClass("HTTP2 GoAway Error",
+		errors.SetPolicy(errors.Policy{Retryable: errors.PolicyYes}))
+	// ConnectionError is a connection-level protocol error
+	// (http2.ConnectionError).
+	ConnectionError = HTTP2Error.NewClass("HTTP2 Connection Error")
+
+	// http2Packages are the packages the http2 error types live in.
+	http2Packages = map[string]bool{
+		"golang.org/x/net/http2": true,
+		"net/http":               true,
+	}
+)
+
+// the http2 REFUSED_STREAM error code.
+const refusedStream = 0x7
+
+// Register adds Classify to the errors package's classifiers.
+func Register() {
+	errors.RegisterClassifier(Classify)
+}
+
+// Classify returns the class for an HTTP/2 error (or an error wrapping one),
+// or nil if err isn't one.
+func Classify(err error) *errors.ErrorClass {
+	for ; err != nil; err = unwrap(err) {
+		val := reflect.ValueOf(err)
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				continue
+			}
+			val = val.Elem()
+		}
+		typ := val.Type()
+		if !http2Packages[typ.PkgPath()] {
+			continue
+		}
+		switch strings.TrimPrefix(typ.Name(), "http2") {
+		case "StreamError":
+			if code := val.FieldByName("Code"); code.IsValid() &&
+				code.Kind() == reflect.Uint32 && code.Uint() == refusedStream {
+				return RefusedStreamError
+			}
+			return StreamError
+		case "GoAwayError":
+			return GoAwayError
+		case "ConnectionError":
+			return ConnectionError
+		}
+	}
+	return nil
+}
+
+func unwrap(err error) error {
+	if wrapper, ok := err.(interface{ Unwrap() error }); ok {
+		return wrapper.Unwrap()
+	}
+	return nil
+}
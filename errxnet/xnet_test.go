@@ -0,0 +1,62 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errxnet
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// These have the shape of the http2 error types bundled into net/http.
+type http2ErrCode uint32
+
+type http2StreamError struct {
+	StreamID uint32
+	Code     http2ErrCode
+}
+
+func (e http2StreamError) Error() string { return "stream error" }
+
+type http2GoAwayError struct{ ErrCode http2ErrCode }
+
+func (e http2GoAwayError) Error() string { return "goaway" }
+
+func TestClassify(t *testing.T) {
+	http2Packages[reflect.TypeOf(http2GoAwayError{}).PkgPath()] = true
+	Register()
+	cases := []struct {
+		err   error
+		class *errors.ErrorClass
+	}{
+		{http2StreamError{StreamID: 1, Code: 0x2}, StreamError},
+		{http2StreamError{StreamID: 1, Code: refusedStream}, RefusedStreamError},
+		{&http2GoAwayError{}, GoAwayError},
+		{fmt.Errorf("roundtrip: %w", http2GoAwayError{}), GoAwayError},
+	}
+	for _, c := range cases {
+		if class := errors.GetClass(c.err); class != c.class {
+			t.Errorf("%v classified as %v, want %v", c.err, class, c.class)
+		}
+	}
+	if !errors.Retryable(http2StreamError{Code: refusedStream}) {
+		t.Error("refused stream not retryable")
+	}
+	if class := Classify(fmt.Errorf("plain")); class != nil {
+		t.Errorf("plain error classified as %v", class)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// ExportedError is an immutable view of an error, as it was when Export was
+// called. Encoders work from this view, so everything written about an error
+// agrees even if code goes on to modify the error afterwards.
+type ExportedError struct {
+	// err is a private clone of the exported *Error, or the exported error
+	// itself if it isn't one.
+	err error
+}
+
+// Export marks err as exported and returns an immutable view of it. Once an
+// error is exported, its logs and remote copies no longer change with it, so
+// it shouldn't be modified any more; set Config.Debugexport to panic when an
+// exported error is modified. To add to an exported error, Clone it.
+func Export(err error) ExportedError {
+	cast, ok := err.(*Error)
+	if !ok {
+		return ExportedError{err: err}
+	}
+	cast.checkLive()
+	// exporting is an escape, for errors with deferred stack capture.
+	cast.escape()
+	atomic.StoreUint32(&cast.exported, 1)
+	return ExportedError{err: cast.clone()}
+}
+
+// IsExported reports whether err has been passed to Export.
+func IsExported(err error) bool {
+	cast, ok := err.(*Error)
+	return ok && atomic.LoadUint32(&cast.exported) != 0
+}
+
+// checkMutable panics if the error has been released, or, with
+// Config.Debugexport set, exported. Annotations and recorded exits are
+// exempt; see Annotate.
+func (e *Error) checkMutable() {
+	e.checkLive()
+	if Config.Debugexport && atomic.LoadUint32(&e.exported) != 0 {
+		panic(ProgrammerError.New("modification of exported %s", e.class))
+	}
+}
+
+// Class returns the error's class, as GetClass does.
+func (x ExportedError) Class() *ErrorClass { return GetClass(x.err) }
+
+// Message returns the error's message, as GetMessage does.
+func (x ExportedError) Message() string { return GetMessage(x.err) }
+
+// Stack returns the error's stack, as GetStack does.
+func (x ExportedError) Stack() string { return GetStack(x.err) }
+
+// HasStack reports whether the error has a stack, as HasStack does.
+func (x ExportedError) HasStack() bool { return HasStack(x.err) }
+
+// Exits returns the error's recorded exits, as GetExits does.
+func (x ExportedError) Exits() string { return GetExits(x.err) }
+
+// GetData returns the error's data for key, as GetData does.
+func (x ExportedError) GetData(key DataKey) interface{} {
+	return GetData(x.err, key)
+}
+
+// innerMessage returns the message of the error wrapped by the exported
+// error, without its class name.
+func (x ExportedError) innerMessage() string {
+	if cast, ok := x.err.(*Error); ok {
+		return strings.TrimRight(GetMessage(cast.err), "\n ")
+	}
+	if x.err == nil {
+		return ""
+	}
+	return x.err.Error()
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	fatalKey = GenSym()
+
+	crashHandler struct {
+		mu sync.RWMutex
+		f  func(err error)
+	}
+
+	// FatalRuntimeError is the class for panics raised by the runtime that
+	// the program considers unsafe to recover from. See IsFatalRuntimePanic.
+	FatalRuntimeError = NewClass("Fatal Runtime Error", LogOnCreation())
+
+	// FatalRuntimePatterns are the runtime.Error message fragments that mark
+	// a panic as a fatal runtime state. It's empty by default: the states the
+	// runtime can't recover from, like unexpected signals, faults outside
+	// debug.SetPanicOnFault and cgo faults, end the process with a fatal
+	// error that never reaches a deferred recover, and the runtime errors
+	// that are panics, including faults under SetPanicOnFault and failed cgo
+	// pointer checks, leave the process in a sound state. Add fragments of
+	// the messages of runtime errors the program shouldn't survive anyway.
+	FatalRuntimePatterns []string
+)
+
+// IsFatalRuntimePanic returns whether the given recovered panic value is a
+// runtime.Error that FatalRuntimePatterns marks unsafe to recover from.
+// Without patterns, no panic is.
+func IsFatalRuntimePanic(rec interface{}) bool {
+	rerr, ok := rec.(runtime.Error)
+	if !ok {
+		return false
+	}
+	message := rerr.Error()
+	for _, pattern := range FatalRuntimePatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fatal declares the error class and its descendents fatal: conditions, like
+// detected data corruption, where any recovery attempt is worse than
+// crashing. The try package never lets handlers consume fatal errors (its
+// Finally blocks still run), and reports them to the crash handler.
+func Fatal() ErrorOption {
+	return SetData(fatalKey, true)
+}
+
+// IsFatal returns whether the error's class was declared Fatal.
+func IsFatal(err error) bool {
+	return boolWrapper(GetData(err, fatalKey), false)
+}
+
+// SetCrashHandler registers the function that ReportFatal hands fatal errors
+// to, typically to flush logs and state before the process dies.
+func SetCrashHandler(f func(err error)) {
+	crashHandler.mu.Lock()
+	defer crashHandler.mu.Unlock()
+	crashHandler.f = f
+}
+
+// ReportFatal passes a fatal error to the crash handler, if one is
+// registered. Each error is only reported once, no matter how many layers of
+// handling it passes through.
+func ReportFatal(err error) {
+	cast, ok := err.(*Error)
+	if ok && !atomic.CompareAndSwapUint32(&cast.fatalReported, 0, 1) {
+		return
+	}
+	crashHandler.mu.RLock()
+	f := crashHandler.f
+	crashHandler.mu.RUnlock()
+	if f != nil {
+		f(err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"iter"
+)
+
+// unwrapAll returns the errors directly wrapped by err, whether it is an
+// *Error or a standard library style wrapper with an Unwrap method.
+func unwrapAll(err error) []error {
+	switch cast := err.(type) {
+	case *Error:
+		return []error{cast.err}
+	case interface{ Unwrap() []error }:
+		return cast.Unwrap()
+	case interface{ Unwrap() error }:
+		if inner := cast.Unwrap(); inner != nil {
+			return []error{inner}
+		}
+	}
+	return nil
+}
+
+// walk calls f on err and every error it wraps, depth first, until f returns
+// true. It reports whether f ever returned true.
+func walk(err error, f func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if f(err) {
+		return true
+	}
+	for _, inner := range unwrapAll(err) {
+		if walk(inner, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindType searches err and the errors it wraps, including through standard
+// library style Unwrap methods, and returns the first one of type T. For
+// example, to find an underlying *net.OpError:
+//
+//	if op, ok := errors.FindType[*net.OpError](err); ok {
+//		...
+//	}
+func FindType[T error](err error) (T, bool) {
+	var found T
+	ok := walk(err, func(candidate error) bool {
+		cast, ok := candidate.(T)
+		if ok {
+			found = cast
+		}
+		return ok
+	})
+	return found, ok
+}
+
+// Layers returns an iterator over the layers of err that belong to the
+// class, outermost first. A layer is an *Error in err's chain of wrapped
+// errors (including through standard library style Unwrap methods); it
+// belongs to the class if its own class does, regardless of the layers it
+// wraps. Handlers can use it to find the layer that actually carries their
+// data.
+func (e *ErrorClass) Layers(err error) iter.Seq[*Error] {
+	return func(yield func(*Error) bool) {
+		walk(err, func(candidate error) bool {
+			cast, ok := candidate.(*Error)
+			if !ok || !e.Contains(cast) {
+				return false
+			}
+			return !yield(cast)
+		})
+	}
+}
+
+// Innermost returns the innermost layer of err that belongs to the class,
+// or nil if there is none. See Layers.
+func (e *ErrorClass) Innermost(err error) *Error {
+	var innermost *Error
+	for layer := range e.Layers(err) {
+		innermost = layer
+	}
+	return innermost
+}
+
+// RootCause returns the innermost error err wraps, following the first
+// wrapped error wherever an error wraps several. It returns err itself if it
+// wraps nothing.
+func RootCause(err error) error {
+	for {
+		inner := unwrapAll(err)
+		if len(inner) == 0 || inner[0] == nil {
+			return err
+		}
+		err = inner[0]
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"errors"
+)
+
+// ForTest makes an error of the receiver class for unit tests of error
+// handling code. The error has exactly the given message, stack lines and
+// error-specific options. Unlike New and Wrap, it never captures the real
+// stack, logs, journals, or counts toward the class' statistics, whatever
+// the class' settings are, so tests stay deterministic and quiet.
+func (e *ErrorClass) ForTest(message string, stack []string,
+	options ...ErrorOption) error {
+	rv := &Error{
+		err:       errors.New(message),
+		class:     e.orRoot(),
+		fakeStack: append([]string{}, stack...)}
+	if len(options) > 0 {
+		rv.data = make(map[DataKey]interface{})
+		for _, option := range options {
+			option(rv.data)
+		}
+	}
+	return rv
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	recordGoroutine    = GenSym()
+	goroutineKey       = GenSym()
+	goroutineLabelsKey = GenSym()
+)
+
+// RecordGoroutine tells the error class and its descendents to record the ID
+// of the goroutine each error is created on, so errors handed between
+// goroutines in a concurrent pipeline can still be traced to the worker that
+// produced them. Goroutine IDs are sequence numbers assigned as goroutines
+// start, and only mean anything for debugging. Finding the ID costs about as
+// much as a short stack capture.
+func RecordGoroutine() ErrorOption {
+	return SetData(recordGoroutine, true)
+}
+
+// GetGoroutine returns the ID of the goroutine the error was created on, if
+// its class records goroutines. For wrapped errors, the innermost recorded
+// layer wins, since that's where the failure started.
+func GetGoroutine(err error) (id uint64, ok bool) {
+	walk(err, func(layer error) bool {
+		if layerID, layerOK := GetData(layer, goroutineKey).(uint64); layerOK {
+			id, ok = layerID, true
+		}
+		return false
+	})
+	return id, ok
+}
+
+// GetGoroutineLabels returns the pprof labels attached to the error by the
+// GoroutineLabels harvester, or nil. For wrapped errors, the innermost
+// labeled layer wins.
+func GetGoroutineLabels(err error) map[string]string {
+	var labels map[string]string
+	walk(err, func(layer error) bool {
+		if l, ok := GetData(layer, goroutineLabelsKey).(map[string]string); ok {
+			labels = l
+		}
+		return false
+	})
+	return labels
+}
+
+// setGoroutine records the current goroutine's ID on the error.
+func (e *Error) setGoroutine() {
+	id, ok := goroutineID()
+	if !ok {
+		return
+	}
+	if e.data == nil {
+		e.data = make(map[DataKey]interface{})
+	}
+	e.data[goroutineKey] = id
+}
+
+// goroutineID parses the current goroutine's ID out of the header of its
+// stack trace, "goroutine 18 [running]:".
+func goroutineID() (uint64, bool) {
+	var buf [64]byte
+	header := string(buf[:runtime.Stack(buf[:], false)])
+	header = strings.TrimPrefix(header, "goroutine ")
+	if end := strings.IndexByte(header, ' '); end > 0 {
+		id, err := strconv.ParseUint(header[:end], 10, 64)
+		return id, err == nil
+	}
+	return 0, false
+}
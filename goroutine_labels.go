@@ -0,0 +1,44 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// GoroutineLabels is a ContextHarvester that attaches the pprof labels in
+// ctx, as set with pprof.Do or pprof.WithLabels, to errors created or wrapped
+// with it. Register it to get labels on errors from labeled workers:
+//
+//	errors.RegisterContextHarvester(errors.GoroutineLabels)
+//
+// The labels are available from GetGoroutineLabels.
+func GoroutineLabels(ctx context.Context) []ErrorOption {
+	var labels map[string]string
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = value
+		return true
+	})
+	if labels == nil {
+		return nil
+	}
+	return []ErrorOption{SetData(goroutineLabelsKey, labels)}
+}
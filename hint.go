@@ -0,0 +1,39 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	hintKey = GenSym()
+)
+
+// SetHint returns an ErrorOption (for use in ErrorClass creation or error
+// instantiation) attaching a remediation hint, such as "increase your quota
+// from the billing console", or a key into a table of such hints. Hints are
+// written for end users, so renderers like errhttp.WriteProblem show them
+// even for errors that aren't UserFacing.
+func SetHint(hint string) ErrorOption {
+	return SetData(hintKey, hint)
+}
+
+// GetHint returns the remediation hint for err: the one set on the
+// outermost layer (or its class) that has one, or the empty string.
+func GetHint(err error) string {
+	var hint string
+	walk(err, func(layer error) bool {
+		hint, _ = GetData(layer, hintKey).(string)
+		return hint != ""
+	})
+	return hint
+}
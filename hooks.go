@@ -0,0 +1,74 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	handledByKey = GenSym()
+)
+
+var hooks struct {
+	mu     sync.RWMutex
+	caught []func(err error)
+}
+
+// OnCaught registers f to be called with every error passed to Caught.
+func OnCaught(f func(err error)) {
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.caught = append(hooks.caught, f)
+}
+
+// Caught should be called by error handlers when they consume an error
+// instead of passing it on. The try package does this automatically. It
+// notifies observers registered with OnCaught.
+func Caught(err error) {
+	CaughtBy(err, "")
+}
+
+// CaughtBy is Caught for handlers that can describe themselves, like
+// "Catch(Fruit Error) at main.main.func2:main.go:20". Before notifying
+// observers it records the handler for GetHandledBy, so the error can be
+// audited for which handlers consumed it. try plans do this when
+// try.AuditHandlers is set.
+func CaughtBy(err error, handler string) {
+	if err == nil {
+		return
+	}
+	if cast, ok := err.(*Error); ok {
+		atomic.AddInt64(&cast.class.caught, 1)
+		if handler != "" {
+			handlers, _ := cast.data[handledByKey].([]string)
+			Annotate(cast, handledByKey,
+				append(handlers[:len(handlers):len(handlers)], handler))
+		}
+	}
+	hooks.mu.RLock()
+	defer hooks.mu.RUnlock()
+	for _, f := range hooks.caught {
+		f(err)
+	}
+}
+
+// GetHandledBy returns the handlers recorded by CaughtBy as consuming err, in
+// the order they ran (innermost first when an error is rethrown), or nil.
+func GetHandledBy(err error) []string {
+	handlers, _ := GetData(err, handledByKey).([]string)
+	return handlers
+}
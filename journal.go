@@ -0,0 +1,292 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	journalOnCreation = GenSym()
+
+	// JournalChainError is returned by VerifyJournal when a journal's hash
+	// chain is broken.
+	JournalChainError = NewClass("Journal Chain Error")
+
+	journalMu     sync.RWMutex
+	activeJournal *Journal
+)
+
+// JournalOnCreation tells the error class and its descendents to append
+// every error of this class to the journal installed with SetJournal when it
+// is created.
+func JournalOnCreation() ErrorOption {
+	return SetData(journalOnCreation, true)
+}
+
+// NoJournalOnCreation is the opposite of JournalOnCreation and applies to the
+// error, class, and its descendents. This is the default.
+func NoJournalOnCreation() ErrorOption {
+	return SetData(journalOnCreation, false)
+}
+
+// SetJournal installs the journal that errors from classes with
+// JournalOnCreation are written to. nil disables journaling.
+func SetJournal(j *Journal) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	activeJournal = j
+}
+
+// JournalEntry is a single line in a Journal.
+type JournalEntry struct {
+	Time    time.Time `json:"time"`
+	Class   string    `json:"class"`
+	Message string    `json:"message"`
+	Stack   []string  `json:"stack,omitempty"`
+	Exits   []string  `json:"exits,omitempty"`
+	// Prev and Hash link entries into a hash chain, if the journal has
+	// EnableHashChain set. Hash is always the last field on the line.
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// Journal appends errors as JSON lines to a file, independent of the logger,
+// so they survive for postmortem analysis even if logs are lost. Once the file
+// grows past its size limit it is rotated to path.1 (and path.1 to path.2,
+// and so on, up to the number of backups kept). Journals are threadsafe.
+type Journal struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	fh       *os.File
+	size     int64
+	closed   bool
+	chained  bool
+	last     string
+}
+
+// OpenJournal opens (creating if needed) a journal at path that rotates
+// once it exceeds maxBytes, keeping the given number of rotated backups.
+// A maxBytes of zero disables rotation.
+func OpenJournal(path string, maxBytes int64, backups int) (*Journal, error) {
+	j := &Journal{path: path, maxBytes: maxBytes, backups: backups}
+	err := j.open()
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) open() error {
+	fh, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return err
+	}
+	j.fh = fh
+	j.size = info.Size()
+	return nil
+}
+
+// rotate moves the journal's file aside and opens a new one. If it fails,
+// the journal is left without a file, for the next Write to reopen.
+func (j *Journal) rotate() error {
+	err := j.fh.Close()
+	j.fh = nil
+	if err != nil {
+		return err
+	}
+	for i := j.backups; i > 0; i-- {
+		from := j.path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", j.path, i-1)
+		}
+		err = os.Rename(from, fmt.Sprintf("%s.%d", j.path, i))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if j.backups == 0 {
+		err = os.Remove(j.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return j.open()
+}
+
+// Write appends the given error to the journal. nil errors are ignored.
+func (j *Journal) Write(err error) error {
+	if err == nil {
+		return nil
+	}
+	entry := JournalEntry{
+		Time:    time.Now(),
+		Class:   GetClass(err).Path(),
+		Message: GetMessage(err)}
+	if stack := GetStack(err); stack != "" {
+		entry.Stack = strings.Split(stack, "\n")
+	}
+	if exits := GetExits(err); exits != "" {
+		entry.Exits = strings.Split(exits, "\n")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return ProgrammerError.New("journal %s is closed", j.path)
+	}
+	if j.fh == nil {
+		if oerr := j.open(); oerr != nil {
+			return oerr
+		}
+	}
+	if j.chained {
+		entry.Prev = j.last
+	}
+	line, merr := json.Marshal(entry)
+	if merr != nil {
+		return merr
+	}
+	var hash string
+	if j.chained {
+		line, hash = chainLine(line)
+	}
+	line = append(line, '\n')
+	if j.maxBytes > 0 && j.size > 0 && j.size+int64(len(line)) > j.maxBytes {
+		if rerr := j.rotate(); rerr != nil {
+			return rerr
+		}
+	}
+	n, werr := j.fh.Write(line)
+	j.size += int64(n)
+	if werr == nil && j.chained {
+		j.last = hash
+	}
+	return werr
+}
+
+// EnableHashChain makes the journal link its entries into a hash chain for
+// audit trails: each entry records the hash of the entry before it (Prev),
+// and its own hash (Hash), a SHA-256 of the rest of its line. Edited,
+// reordered or deleted entries then break the chain, which VerifyJournal
+// detects. The chain continues across rotations, and picks up from the last
+// entry already in the journal's file.
+func (j *Journal) EnableHashChain() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if last := lines[len(lines)-1]; last != "" {
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(last), &entry); err != nil {
+			return err
+		}
+		j.last = entry.Hash
+	}
+	j.chained = true
+	return nil
+}
+
+// chainLine appends the hash field to an encoded entry, returning the new
+// line and the hash.
+func chainLine(line []byte) ([]byte, string) {
+	sum := sha256.Sum256(line)
+	hash := hex.EncodeToString(sum[:])
+	line = append(line[:len(line)-1], `,"hash":"`...)
+	line = append(line, hash...)
+	return append(line, `"}`...), hash
+}
+
+// VerifyJournal checks the hash chain of a journal file written with
+// EnableHashChain, returning the hash of its last entry. prev is the hash
+// the first entry must follow: the empty string for the first file of a
+// chain, or the hash VerifyJournal returned for the file rotated out before
+// it (check path.N down to path.1, then path). It fails with a
+// JournalChainError naming the first line that doesn't check out.
+func VerifyJournal(r io.Reader, prev string) (last string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Bytes()
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return "", JournalChainError.New("line %d: %s", lineno, err)
+		}
+		suffix := `,"hash":"` + entry.Hash + `"}`
+		if entry.Hash == "" || !bytes.HasSuffix(line, []byte(suffix)) {
+			return "", JournalChainError.New("line %d: missing hash", lineno)
+		}
+		body := append(line[:len(line)-len(suffix):len(line)-len(suffix)], '}')
+		if _, hash := chainLine(body); hash != entry.Hash {
+			return "", JournalChainError.New("line %d: hash mismatch", lineno)
+		}
+		if entry.Prev != prev {
+			return "", JournalChainError.New(
+				"line %d: doesn't follow the previous entry", lineno)
+		}
+		prev = entry.Hash
+	}
+	return prev, scanner.Err()
+}
+
+// Close closes the journal's file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.closed = true
+	if j.fh == nil {
+		return nil
+	}
+	err := j.fh.Close()
+	j.fh = nil
+	return err
+}
+
+// journal writes err to the active journal if its class asks for it, logging
+// any failure to do so.
+func journal(err *Error) {
+	if !boolWrapper(err.GetData(journalOnCreation), false) {
+		return
+	}
+	journalMu.RLock()
+	j := activeJournal
+	journalMu.RUnlock()
+	if j == nil {
+		return
+	}
+	if jerr := j.Write(err); jerr != nil {
+		LogMethod("failed writing error journal: %s", jerr)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sort"
+	"time"
+)
+
+// Kinds of JourneyEvent.
+const (
+	JourneyCreated      = "created"
+	JourneyWrapped      = "wrapped"
+	JourneyExited       = "exited"
+	JourneySerialized   = "serialized"
+	JourneyDeserialized = "deserialized"
+)
+
+// JourneyEvent is a step in an error's life, as returned by Journey. Events
+// of errors rehydrated by UnmarshalError include those recorded in the
+// process that encoded them.
+type JourneyEvent struct {
+	Kind     string    `json:"kind"`
+	Class    string    `json:"class"`
+	Location string    `json:"location,omitempty"`
+	Label    string    `json:"label,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// maxTravels bounds the serialized and deserialized events kept per error,
+// so errors serialized over and over, as by retries, don't grow without end.
+const maxTravels = 16
+
+// Journey returns the recorded events in the life of err and the errors it
+// wraps, oldest first: where the innermost error was created, where outer
+// classes wrapped it, and every exit recorded along the way (including plans
+// and channels it crossed, which are labeled). With Config.Journeys set, it
+// also has the latest places it was serialized (by MarshalError,
+// EncodeBinary or ExportABI) and deserialized (by UnmarshalError,
+// BinaryError.Rehydrate or ImportABI), and MarshalError carries it to the
+// process rehydrating the error. It's meant for tools that visualize how an
+// error traveled across services; the events encode directly to JSON.
+func Journey(err error) []JourneyEvent {
+	var layers []*Error
+	for {
+		cast, ok := err.(*Error)
+		if !ok {
+			break
+		}
+		layers = append(layers, cast)
+		err = cast.err
+	}
+	var events []JourneyEvent
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		class := layer.class.Path()
+		// rehydrated errors weren't created here; their creation is among
+		// the events they traveled with.
+		if !layer.created.IsZero() {
+			event := JourneyEvent{
+				Kind:  JourneyCreated,
+				Class: class,
+				Time:  layer.created}
+			if i != len(layers)-1 {
+				event.Kind = JourneyWrapped
+			}
+			if len(layer.stack) > 0 {
+				event.Location = layer.stack[0].String()
+			}
+			events = append(events, event)
+		}
+		layer.travelMu.Lock()
+		events = append(events, layer.travels...)
+		layer.travelMu.Unlock()
+		for _, ex := range layer.exits {
+			events = append(events, JourneyEvent{
+				Kind:     JourneyExited,
+				Class:    class,
+				Location: frame{pc: ex.pc}.String(),
+				Label:    ex.label,
+				Time:     ex.at})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// travel records a JourneySerialized or JourneyDeserialized event for err,
+// if it's an *Error and Config.Journeys is set, located depth callers up.
+func travel(err error, kind string, depth int) {
+	cast, ok := err.(*Error)
+	if !ok || !Config.Journeys {
+		return
+	}
+	cast.checkLive()
+	event := JourneyEvent{Kind: kind, Class: cast.class.Path(),
+		Time: time.Now()}
+	if f := callerState(depth + 1); f.pc != 0 {
+		event.Location = f.String()
+	}
+	cast.travelMu.Lock()
+	cast.travels = latestTravels(append(cast.travels, event))
+	cast.travelMu.Unlock()
+}
+
+// latestTravels returns the last maxTravels of events.
+func latestTravels(events []JourneyEvent) []JourneyEvent {
+	if len(events) <= maxTravels {
+		return events
+	}
+	return append([]JourneyEvent(nil), events[len(events)-maxTravels:]...)
+}
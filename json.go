@@ -0,0 +1,135 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// JSONError is the JSON form of an error made by MarshalError and
+// (*Error).MarshalJSON. Class is the path of the error's class, Message its
+// message without the class name, Data the values stored under keys named
+// with ExportDataKey, Stack the rendered frames of its stack, and Journey,
+// only with Config.Journeys set, its Journey up to and including its
+// serialization. Unlike EncodedError it's meant for round trips:
+// UnmarshalError turns it back into an error of the same class.
+type JSONError struct {
+	Class   string                 `json:"class"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Stack   []string               `json:"stack,omitempty"`
+	Journey []JourneyEvent         `json:"journey,omitempty"`
+}
+
+// MarshalError encodes err as a JSONError. Errors that weren't created
+// through this package are encoded with their system class, as GetClass
+// reports it, and their full message.
+func MarshalError(err error) ([]byte, error) {
+	return marshalError(err, 2)
+}
+
+// marshalError is MarshalError, for a caller depth callers up.
+func marshalError(err error, depth int) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	x := Export(err)
+	enc := JSONError{
+		Class:   x.Class().Path(),
+		Message: x.innerMessage()}
+	if stack := x.Stack(); stack != "" {
+		enc.Stack = strings.Split(stack, "\n")
+	}
+	if _, ok := err.(*Error); ok {
+		if Config.Journeys {
+			travel(err, JourneySerialized, depth+1)
+			enc.Journey = Journey(err)
+		}
+		abiKeys.RLock()
+		for key, name := range abiKeys.byKey {
+			if val := x.GetData(key); val != nil {
+				if enc.Data == nil {
+					enc.Data = make(map[string]interface{})
+				}
+				enc.Data[name] = val
+			}
+		}
+		abiKeys.RUnlock()
+	}
+	return json.Marshal(enc)
+}
+
+// MarshalJSON encodes the error as a JSONError, so errors can be embedded in
+// JSON API responses and rehydrated by clients with UnmarshalError.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return marshalError(e, 2)
+}
+
+// UnmarshalJSON sets the receiver, which should be a new zero Error, to the
+// error encoded by data, as UnmarshalError does.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	return e.unmarshalJSON(data, 2)
+}
+
+// unmarshalJSON is UnmarshalJSON, for a caller depth callers up.
+func (e *Error) unmarshalJSON(data []byte, depth int) error {
+	var enc JSONError
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return err
+	}
+	e.err = errors.New(enc.Message)
+	e.class = LookupClass(enc.Class)
+	if e.class == nil {
+		e.class = HierarchicalError
+	}
+	e.data = nil
+	if len(enc.Data) > 0 {
+		e.data = make(map[DataKey]interface{})
+		abiKeys.RLock()
+		for name, val := range enc.Data {
+			if key, ok := abiKeys.byName[name]; ok {
+				e.data[key] = val
+			}
+		}
+		abiKeys.RUnlock()
+	}
+	e.fakeStack = enc.Stack
+	e.travels = nil
+	if Config.Journeys {
+		e.travels = latestTravels(enc.Journey)
+		travel(e, JourneyDeserialized, depth+1)
+	}
+	return nil
+}
+
+// UnmarshalError rebuilds an error encoded by MarshalError, bound to the
+// class registered here under the encoded path (see LookupClass), so
+// Contains and try's Catch work on errors received from another process. If
+// the class isn't registered the error belongs to HierarchicalError. The
+// error keeps the encoded stack as its own, and data under names unknown to
+// ExportDataKey is dropped. The values of known names come back as the
+// generic JSON types (float64, string, map[string]interface{} and so on).
+func UnmarshalError(data []byte) (error, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+	rv := new(Error)
+	if err := rv.unmarshalJSON(data, 2); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var (
+	deferStack = GenSym()
+
+	// pkgPrefix is the prefix of the names of this package's functions.
+	pkgPrefix = reflect.TypeOf((*Error)(nil)).Elem().PkgPath() + "."
+)
+
+// DeferStackCapture tells the error class and its descendents to record just
+// the pc an error was created at, and to capture the full stack only when
+// the error first escapes: the first time its stack is needed, as when it's
+// logged with Error, reported or exported. The stack is then the one the
+// error escaped through, below the creation frame. Errors that
+// are handled and discarded never pay for a stack, which is a large saving
+// for classes that are mostly handled, at the cost of the frames between
+// creation and escape. It has no effect on classes that don't capture
+// stacks.
+func DeferStackCapture() ErrorOption {
+	return SetData(deferStack, true)
+}
+
+// recordCreation records just the creation pc, skip frames up, for errors
+// whose stack capture is deferred.
+func (e *Error) recordCreation(skip int) {
+	var pc [1]uintptr
+	if runtime.Callers(skip, pc[:]) == 0 {
+		return
+	}
+	e.stack = append(e.stack[:0], frame{pc: pc[0], label: "created"})
+	e.stackDeferred = true
+}
+
+// escape captures the stack of an error whose capture is deferred, on the
+// goroutine the error is escaping through. It only captures program
+// counters; resolving them is left to Stack and StackFrames, so it's cheap
+// enough to call before handing the error to another goroutine, as
+// SymbolizeAsync does.
+func (e *Error) escape() {
+	if !e.stackDeferred {
+		return
+	}
+	e.escapeOnce.Do(e.captureDeferred)
+}
+
+// captureDeferred appends the stack the error is escaping through to its
+// creation frame, leaving out the frames inside this package.
+func (e *Error) captureDeferred() {
+	e.stackDeferred = false
+	var buf [256]uintptr
+	pcs := stackBuffer(&buf)
+	amount := runtime.Callers(2, pcs)
+	start := 0
+	for ; start < amount; start++ {
+		f := runtime.FuncForPC(pcs[start] - 1)
+		if f == nil {
+			break
+		}
+		name := f.Name()
+		if !strings.HasPrefix(name, pkgPrefix) &&
+			!strings.HasPrefix(name, "sync.") &&
+			!strings.HasPrefix(name, "runtime.") {
+			break
+		}
+	}
+	for _, pc := range pcs[start:amount] {
+		e.stack = append(e.stack, frame{pc: pc})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// degradedDataLen is how long string data values are cut to on errors
+	// created over the memory cap.
+	degradedDataLen = 64
+	// dataEntrySize approximates what a data map entry retains.
+	dataEntrySize = 48
+)
+
+var (
+	retainedBytes int64
+	degradedCount uint64
+)
+
+// RetainedBytes returns the approximate number of bytes retained by live
+// errors, as tracked while Config.Memorycap is set. It counts the errors
+// themselves, their stacks and their data, but not the errors they wrap.
+func RetainedBytes() int64 {
+	return atomic.LoadInt64(&retainedBytes)
+}
+
+// DegradedCount returns how many errors have been created degraded because
+// Config.Memorycap was exceeded.
+func DegradedCount() uint64 {
+	return atomic.LoadUint64(&degradedCount)
+}
+
+// degrade reports whether the memory cap is exceeded, and if so, cuts the
+// error's long string data values short (its stack is skipped by wrap).
+// Error storms that pile up millions of errors in queues then degrade
+// instead of exhausting memory.
+func (e *Error) degrade() bool {
+	limit := Config.Memorycap
+	if limit <= 0 || atomic.LoadInt64(&retainedBytes) < int64(limit) {
+		return false
+	}
+	atomic.AddUint64(&degradedCount, 1)
+	for key, val := range e.data {
+		if s, ok := val.(string); ok && len(s) > degradedDataLen {
+			e.data[key] = s[:degradedDataLen] + "..."
+		}
+	}
+	return true
+}
+
+// accountMemory adds the error's approximate size to RetainedBytes until
+// it's garbage collected. Pooled errors aren't tracked, since they're
+// recycled rather than collected.
+func (e *Error) accountMemory() {
+	if Config.Memorycap <= 0 || e.pooled {
+		return
+	}
+	size := int64(unsafe.Sizeof(*e)) +
+		int64(len(e.stack))*int64(unsafe.Sizeof(frame{})) +
+		int64(len(e.data))*dataEntrySize
+	for _, val := range e.data {
+		if s, ok := val.(string); ok {
+			size += int64(len(s))
+		}
+	}
+	atomic.AddInt64(&retainedBytes, size)
+	runtime.AddCleanup(e, func(size int64) {
+		atomic.AddInt64(&retainedBytes, -size)
+	}, size)
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"strings"
+)
+
+var (
+	// MetricLabelDepth is how many levels below the root class MetricLabel
+	// distinguishes.
+	MetricLabelDepth = 1
+)
+
+// MetricLabel returns a label for err with bounded cardinality, suitable as
+// a metrics label value: the names of its class' ancestors from the root
+// down to MetricLabelDepth levels below it, joined by "/". Messages and data
+// never leak into the label, so the number of distinct labels is bounded by
+// the taxonomy. It returns the empty string for nil errors.
+func MetricLabel(err error) string {
+	if err == nil {
+		return ""
+	}
+	var names []string
+	for c := GetClass(err); c != nil; c = c.parent {
+		names = append(names, c.name)
+	}
+	if len(names) > MetricLabelDepth+1 {
+		names = names[len(names)-MetricLabelDepth-1:]
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "/")
+}
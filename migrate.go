@@ -0,0 +1,140 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	// aliases maps the former paths of renamed and reparented classes to
+	// the classes, and history lists each class' former paths in order,
+	// both under registry.mu.
+	aliases map[string]*ErrorClass
+	history map[*ErrorClass][]string
+)
+
+// Rename changes the class' name, and so its path and the paths of its
+// descendents. Their former paths are kept as aliases: LookupClass, and so
+// everything rehydrating errors by path (UnmarshalBinary, errexit, errors
+// from other copies of this package), still finds the classes under them.
+// Like creating classes, migrating them is for initialization, before
+// errors of the affected classes are made or matched concurrently.
+//
+// Rename panics with a ProgrammerError, leaving the class as it was, if class
+// is a root class or a new path has the ClassID of another.
+func Rename(class *ErrorClass, name string) {
+	migrate(class, func() { class.name = name })
+}
+
+// Reparent moves the class, with its descendents, under parent, keeping
+// their former paths as aliases as Rename does. Afterwards the class is
+// (and matches in Is, Contains and IsAny as) a descendent of parent instead
+// of its former parent. Options the class inherited from its former parent
+// when it was created are kept, and parent's are not inherited.
+//
+// Reparent panics with a ProgrammerError, leaving the class as it was, if
+// class is a root class, if parent is class or one of its descendents, or if
+// a new path has the ClassID of another.
+func Reparent(class *ErrorClass, parent *ErrorClass) {
+	parent = parent.orRoot()
+	if parent.Is(class) {
+		panic(ProgrammerError.New(
+			"can't reparent error class %q under its descendent %q",
+			class.Path(), parent.Path()))
+	}
+	migrate(class, func() {
+		class.parent = parent
+		class.reancestor()
+	})
+}
+
+// PathHistory returns the paths the class was known by before it, or one of
+// its ancestors, was renamed or reparented, oldest first.
+func PathHistory(class *ErrorClass) []string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	var rv []string
+	for _, path := range history[class] {
+		if aliases[path] == class {
+			rv = append(rv, path)
+		}
+	}
+	return rv
+}
+
+// migrate applies change to class, moving it and its descendents in the
+// registry from their old paths to their new ones.
+func migrate(class *ErrorClass, change func()) {
+	if class == nil || class.parent == nil {
+		panic(ProgrammerError.New("can't migrate root error class %q",
+			class.Path()))
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	var moved []*ErrorClass
+	old := make(map[*ErrorClass]string)
+	for _, c := range registry.classes {
+		if c.Is(class) {
+			moved = append(moved, c)
+			old[c] = c.Path()
+		}
+	}
+	name, parent := class.name, class.parent
+	change()
+	for _, c := range moved {
+		if other := sharesID(c.Path()); other != "" {
+			path := c.Path()
+			class.name = name
+			if class.parent != parent {
+				class.parent = parent
+				class.reancestor()
+			}
+			panic(ProgrammerError.New(
+				"error class %q has the same ClassID as %q", path, other))
+		}
+	}
+	if aliases == nil {
+		aliases = make(map[string]*ErrorClass)
+		history = make(map[*ErrorClass][]string)
+	}
+	for _, c := range moved {
+		path := old[c]
+		if registry.byPath[path] == c {
+			delete(registry.byPath, path)
+		}
+		aliases[path] = c
+		history[c] = append(history[c], path)
+		_, taken := registry.byPath[c.Path()]
+		if _, ok := c.data[namespaceKey].(string); !ok || !taken {
+			registry.byPath[c.Path()] = c
+		}
+		delete(aliases, c.Path())
+		registry.byID[pathID(c.Path())] = c.Path()
+	}
+}
+
+// reancestor recomputes the ancestry sets of the class and its descendents
+// after it has been reparented. registry.mu must be held.
+func (e *ErrorClass) reancestor() {
+	ancestry := make([]uint64, len(e.parent.ancestry))
+	copy(ancestry, e.parent.ancestry)
+	if e.bit/64 >= len(ancestry) {
+		ancestry = append(ancestry, make([]uint64, e.bit/64+1-len(ancestry))...)
+	}
+	ancestry[e.bit/64] |= 1 << uint(e.bit%64)
+	e.ancestry = ancestry
+	for _, c := range registry.classes {
+		if c.parent == e {
+			c.reancestor()
+		}
+	}
+}
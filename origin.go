@@ -0,0 +1,66 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"runtime"
+	"strings"
+)
+
+// panicOrigin returns the pc of the frame that raised the panic currently
+// being recovered, or 0 if there is no panic in progress. It must be called
+// (indirectly) from a deferred function.
+func panicOrigin() uintptr {
+	var pcs [64]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	panicking := false
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.gopanic" {
+			panicking = true
+		} else if panicking && !strings.HasPrefix(f.Function, "runtime.") {
+			return f.PC
+		}
+		if !more {
+			return 0
+		}
+	}
+}
+
+// RecordPanicOrigin notes on err the location of the panic currently being
+// recovered, if it hasn't noted one already. Call it from the deferred
+// function that recovered err; the try package and CatchPanic do this for
+// you. Returns the given error argument. See PanicOrigin.
+func RecordPanicOrigin(err error) error {
+	cast, ok := err.(*Error)
+	if !ok || cast.origin != 0 {
+		return err
+	}
+	cast.origin = panicOrigin()
+	return err
+}
+
+// PanicOrigin returns where the panic that raised err happened, as recorded
+// by RecordPanicOrigin: the deepest frame outside the runtime. ok is false if
+// no origin was recorded.
+func PanicOrigin(err error) (file string, line int, fn string, ok bool) {
+	cast, isError := err.(*Error)
+	if !isError || cast.origin == 0 {
+		return "", 0, "", false
+	}
+	frames := runtime.CallersFrames([]uintptr{cast.origin})
+	f, _ := frames.Next()
+	return f.File, f.Line, f.Function, true
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+)
+
+var (
+	// UnloadedPluginError is the tombstone class errors from unloaded
+	// plugins decay to. See Plugin.Unload.
+	UnloadedPluginError = NewClass("Unloaded Plugin Error")
+
+	plugins = struct {
+		sync.Mutex
+		loaded map[string]*Plugin
+	}{loaded: make(map[string]*Plugin)}
+)
+
+// Plugin is a sub-registry for the error classes of a dynamically loaded
+// module. Its classes descend from its Root class, which is namespaced with
+// the plugin's name and exempt from FreezeRegistry.
+type Plugin struct {
+	name    string
+	root    *ErrorClass
+	classes []*ErrorClass
+}
+
+// LoadPlugin creates the sub-registry for the named plugin. Call it when the
+// plugin is loaded, and create the plugin's classes from Root (or with
+// Plugin.NewClass). It fails with a ProgrammerError if a plugin with the same
+// name is already loaded.
+func LoadPlugin(name string, options ...ErrorOption) (*Plugin, error) {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if _, exists := plugins.loaded[name]; exists {
+		return nil, ProgrammerError.New("plugin %q is already loaded", name)
+	}
+	options = append([]ErrorOption{Namespace(name), Dynamic()}, options...)
+	p := &Plugin{
+		name: name,
+		root: NewClass(name, options...)}
+	plugins.loaded[name] = p
+	return p, nil
+}
+
+// Root returns the plugin's root class.
+func (p *Plugin) Root() *ErrorClass {
+	return p.root
+}
+
+// NewClass creates a class belonging to the plugin under a parent outside of
+// it, such as a host application class the plugin's errors should match.
+// Classes descending from Root belong to the plugin automatically.
+func (p *Plugin) NewClass(parent *ErrorClass, name string,
+	options ...ErrorOption) *ErrorClass {
+	options = append([]ErrorOption{Namespace(p.name), Dynamic()}, options...)
+	class := parent.NewClass(name, options...)
+	plugins.Lock()
+	p.classes = append(p.classes, class)
+	plugins.Unlock()
+	return class
+}
+
+// belongs returns whether the class belongs to the plugin.
+func (p *Plugin) belongs(class *ErrorClass) bool {
+	if class.Is(p.root) {
+		return true
+	}
+	for _, owned := range p.classes {
+		if class.Is(owned) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unload removes the plugin's classes from the registry, so Classes and
+// LookupClass no longer see them, and turns them into tombstones: errors
+// made from them (which may outlive the plugin) now belong to
+// UnloadedPluginError too, while keeping their messages and their classes'
+// relations to each other. Call it once
+// the plugin's code has stopped running. The plugin can then be loaded
+// again, with fresh classes.
+func (p *Plugin) Unload() {
+	plugins.Lock()
+	defer plugins.Unlock()
+	if plugins.loaded[p.name] != p {
+		return
+	}
+	delete(plugins.loaded, p.name)
+
+	// tombstone only after every class has been checked, since tombstoning
+	// changes what belongs reports.
+	for _, class := range unregister(p.belongs) {
+		class.canon.Store(tombstone(class))
+	}
+}
+
+// tombstone returns the class an unloaded class is interned to: a child of
+// UnloadedPluginError, named with the class' path, that shares the class'
+// bit and ancestors. Each unloaded class gets its own, so unrelated classes
+// don't become the same class by both being unloaded.
+func tombstone(class *ErrorClass) *ErrorClass {
+	data := make(map[DataKey]interface{}, len(class.data))
+	for key, val := range class.data {
+		if key != namespaceKey {
+			data[key] = val
+		}
+	}
+	tomb := &ErrorClass{
+		parent:   UnloadedPluginError,
+		name:     class.Path(),
+		data:     data,
+		isolated: class.isolated,
+		bit:      class.bit}
+	size := len(class.ancestry)
+	if len(UnloadedPluginError.ancestry) > size {
+		size = len(UnloadedPluginError.ancestry)
+	}
+	tomb.ancestry = make([]uint64, size)
+	copy(tomb.ancestry, class.ancestry)
+	for i, word := range UnloadedPluginError.ancestry {
+		tomb.ancestry[i] |= word
+	}
+	return tomb
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	policyKey = GenSym()
+)
+
+// PolicyFlag is a yes/no Policy setting. The zero value leaves the decision
+// to an ancestor class.
+type PolicyFlag int
+
+const (
+	PolicyUnset PolicyFlag = iota
+	PolicyYes
+	PolicyNo
+)
+
+// Policy holds the operational policy for an error class subtree: how retry
+// loops, circuit breakers, reporters and HTTP handlers should treat its
+// errors. Zero-valued fields are unset, and are inherited from the nearest
+// ancestor class that sets them, so policy is declared once alongside the
+// taxonomy instead of in each subsystem's config.
+type Policy struct {
+	Retryable  PolicyFlag
+	MaxRetries int
+	// Breaker says whether errors should count against a circuit breaker.
+	Breaker PolicyFlag
+	// Report names the reporter errors should be sent to, e.g. "sentry".
+	Report     string
+	HTTPStatus int
+}
+
+// SetPolicy returns an ErrorOption that attaches operational policy to the
+// error or error class and its descendents. Fields left unset in policy are
+// inherited from the nearest ancestor class that sets them, or taken from
+// other SetPolicy options given alongside.
+func SetPolicy(policy Policy) ErrorOption {
+	return func(m map[DataKey]interface{}) {
+		m[policyKey] = policyData(m).merge(policy)
+	}
+}
+
+// merge returns p with the set fields of other applied over it.
+func (p Policy) merge(other Policy) Policy {
+	if other.Retryable != PolicyUnset {
+		p.Retryable = other.Retryable
+	}
+	if other.MaxRetries != 0 {
+		p.MaxRetries = other.MaxRetries
+	}
+	if other.Breaker != PolicyUnset {
+		p.Breaker = other.Breaker
+	}
+	if other.Report != "" {
+		p.Report = other.Report
+	}
+	if other.HTTPStatus != 0 {
+		p.HTTPStatus = other.HTTPStatus
+	}
+	return p
+}
+
+// GetPolicy returns the operational policy for the given error, resolving
+// each field from the nearest class (or the error itself) that sets it.
+// Standard library errors get the policy of the class GetClass finds for
+// them. It returns the zero Policy if none was set.
+func GetPolicy(err error) Policy {
+	cast, ok := err.(*Error)
+	if !ok {
+		if class := GetClass(err); class != nil {
+			return class.Policy()
+		}
+		return Policy{}
+	}
+	if boolWrapper(cast.data[disableInheritance], false) {
+		return policyData(cast.data)
+	}
+	return cast.class.Policy().merge(policyData(cast.data))
+}
+
+// Policy returns the operational policy errors of this class inherit. See
+// GetPolicy.
+func (e *ErrorClass) Policy() Policy {
+	classes := e.lineage()
+	var policy Policy
+	for i := len(classes) - 1; i >= 0; i-- {
+		policy = policy.merge(policyData(classes[i].data))
+	}
+	return policy
+}
+
+// lineage returns the class and the ancestors it inherits settings from,
+// nearest first, stopping at the first created with DisableInheritance.
+func (e *ErrorClass) lineage() (classes []*ErrorClass) {
+	for class := e; class != nil; class = class.parent {
+		classes = append(classes, class)
+		if class.isolated {
+			break
+		}
+	}
+	return classes
+}
+
+func policyData(data map[DataKey]interface{}) Policy {
+	p, _ := data[policyKey].(Policy)
+	return p
+}
+
+// Retryable reports whether the error's policy says it should be retried.
+func Retryable(err error) bool {
+	return GetPolicy(err).Retryable == PolicyYes
+}
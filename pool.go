@@ -0,0 +1,92 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	pooledKey = GenSym()
+
+	errorPool = sync.Pool{New: func() interface{} { return new(Error) }}
+)
+
+// Pooled tells the error class and its descendents to allocate their errors
+// from a pool, for services creating huge numbers of short-lived errors. An
+// error from a pooled class must not be used after Release is called on it;
+// the try package releases errors once a handler has consumed them, so
+// handlers must not hold on to pooled errors. Set Config.Debugpool to detect
+// use after release. Unlike most options, Pooled only has an effect on error
+// classes, not on individual errors.
+func Pooled() ErrorOption {
+	return SetData(pooledKey, true)
+}
+
+// newError returns an error of the given class wrapping err, from the pool
+// if the class is pooled.
+func newError(err error, class *ErrorClass) *Error {
+	if !boolWrapper(class.data[pooledKey], false) {
+		return &Error{err: err, class: class}
+	}
+	rv := errorPool.Get().(*Error)
+	rv.err = err
+	rv.class = class
+	rv.pooled = true
+	rv.released = false
+	return rv
+}
+
+// Release returns the error to its class' pool, if the class is Pooled. It
+// does nothing for errors from other classes. The error must not be used
+// afterwards. With Config.Debugpool set, released errors are never reused,
+// and any later use panics with a ProgrammerError.
+func (e *Error) Release() {
+	if e == nil || !e.pooled {
+		return
+	}
+	e.checkLive()
+	e.released = true
+	if Config.Debugpool {
+		return
+	}
+	for key := range e.data {
+		delete(e.data, key)
+	}
+	e.id = 0
+	e.origin = 0
+	e.fatalReported = 0
+	e.exported = 0
+	e.created = time.Time{}
+	e.err = nil
+	e.class = nil
+	e.stack = e.stack[:0]
+	e.fakeStack = nil
+	e.stackDeferred = false
+	e.escapeOnce = sync.Once{}
+	e.stackOnce = sync.Once{}
+	e.stackText = ""
+	e.exits = e.exits[:0]
+	e.travels = nil
+	errorPool.Put(e)
+}
+
+// checkLive panics if the error has been released.
+func (e *Error) checkLive() {
+	if e.released {
+		panic(ProgrammerError.New("use of released error"))
+	}
+}
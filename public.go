@@ -0,0 +1,87 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	userFacing = GenSym()
+
+	lastErrorId uint64
+	idPrefix    = fmt.Sprintf("%x", time.Now().UnixNano()&0xffffff)
+)
+
+// UserFacing marks the error class and its descendents as safe to show to
+// end users. Renderers such as errhttp.GetErrorBody show messages of
+// user-facing errors, and a generic "internal error (id: X)" otherwise.
+func UserFacing() ErrorOption {
+	return SetData(userFacing, true)
+}
+
+// Internal is the opposite of UserFacing and applies to the error, class, and
+// its descendents. This is the default.
+func Internal() ErrorOption {
+	return SetData(userFacing, false)
+}
+
+// IsUserFacing returns whether the error's class was marked UserFacing.
+func IsUserFacing(err error) bool {
+	return boolWrapper(GetData(err, userFacing), false)
+}
+
+// ID returns an identifier for the error, unique within the process, which is
+// assigned the first time it's asked for. Log it next to the error to let
+// users quote it in reports about generic internal errors.
+func (e *Error) ID() string {
+	return fmt.Sprintf("%s-%d", idPrefix, e.idNum())
+}
+
+// idNum returns the error's ID number, assigning one if need be.
+func (e *Error) idNum() uint64 {
+	id := atomic.LoadUint64(&e.id)
+	if id == 0 {
+		atomic.CompareAndSwapUint64(&e.id, 0, atomic.AddUint64(&lastErrorId, 1))
+		id = atomic.LoadUint64(&e.id)
+	}
+	return id
+}
+
+// GetID returns the error's ID, or the empty string if it isn't an *Error.
+func GetID(err error) string {
+	cast, ok := err.(*Error)
+	if !ok {
+		return ""
+	}
+	return cast.ID()
+}
+
+// PublicMessage returns the message to show an end user for the error: its
+// message if it's UserFacing, or a generic internal error naming its ID.
+func PublicMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	if IsUserFacing(err) {
+		return GetMessage(err)
+	}
+	if id := GetID(err); id != "" {
+		return fmt.Sprintf("internal error (id: %s)", id)
+	}
+	return "internal error"
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+)
+
+var (
+	// OnUndeclaredRaise is called by CheckRaises, when Config.Checkraises is
+	// set, with the id of a boundary and an error escaping it whose class
+	// the boundary didn't declare. It runs in the boundary's deferred
+	// CheckRaises, on the goroutine returning the error, so panicking stops
+	// the error at the boundary. The default logs the class and message and
+	// lets the error through.
+	OnUndeclaredRaise = func(id interface{}, err error) {
+		LogMethod("errors: %v raised undeclared %s: %s", id,
+			GetClass(err).Path(), GetMessage(err))
+	}
+
+	raises struct {
+		mu       sync.RWMutex
+		declared map[interface{}][]*ErrorClass
+	}
+)
+
+// Raises declares that the function or package identified by id, any
+// comparable value such as a string naming it, may raise errors of classes
+// and their descendents. Declarations add up, and are typically made in an
+// init function next to the code they describe:
+//
+//	func init() {
+//		errors.Raises("fruit.Pick", FruitError, IOError)
+//	}
+//
+// Declarations are only metadata until checked, by CheckRaises and try's
+// Plan.Raises in debug builds, so they cost nothing in production.
+func Raises(id interface{}, classes ...*ErrorClass) {
+	raises.mu.Lock()
+	defer raises.mu.Unlock()
+	if raises.declared == nil {
+		raises.declared = make(map[interface{}][]*ErrorClass)
+	}
+	raises.declared[id] = append(raises.declared[id], classes...)
+}
+
+// DeclaredRaises returns the classes declared for id with Raises, or nil if
+// none were.
+func DeclaredRaises(id interface{}) []*ErrorClass {
+	raises.mu.RLock()
+	defer raises.mu.RUnlock()
+	return append([]*ErrorClass(nil), raises.declared[id]...)
+}
+
+// Undeclared reports whether err is an error the classes declared for id
+// don't cover. Nil errors, and all errors of ids that declared nothing, are
+// covered.
+func Undeclared(id interface{}, err error) bool {
+	if err == nil {
+		return false
+	}
+	raises.mu.RLock()
+	declared := raises.declared[id]
+	raises.mu.RUnlock()
+	return declared != nil && !IsAny(err, declared...)
+}
+
+// CheckRaises calls OnUndeclaredRaise if Config.Checkraises is set and the
+// error *errp isn't covered by the classes declared for id. It's for a defer
+// at a boundary:
+//
+//	func Pick(tree *Tree) (fruit *Fruit, err error) {
+//		defer errors.CheckRaises("fruit.Pick", &err)
+//		...
+//	}
+func CheckRaises(id interface{}, errp *error) {
+	if Config.Checkraises && Undeclared(id, *errp) {
+		OnUndeclaredRaise(id, *errp)
+	}
+}
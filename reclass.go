@@ -0,0 +1,59 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync/atomic"
+)
+
+var (
+	originalClassKey = GenSym()
+)
+
+// Reclass returns err recategorized as class, for boundary layers that must
+// re-categorize failures, such as mapping a vendor's transient error into a
+// retryable family. An *Error keeps its message, ID, data, stack and exits;
+// only its class (and so the class data it inherits) changes. Any other
+// error is wrapped in class. Either way the original class is recorded for
+// GetOriginalClass. err itself is not modified.
+func Reclass(err error, class *ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+	original := GetOriginalClass(err)
+	if original == nil {
+		original = GetClass(err)
+	}
+	cast, ok := err.(*Error)
+	if !ok {
+		return class.Wrap(err, SetData(originalClassKey, original))
+	}
+	rv := cast.clone()
+	rv.class = class
+	if rv.data == nil {
+		rv.data = make(map[DataKey]interface{}, 1)
+	}
+	rv.data[originalClassKey] = original
+	atomic.AddInt64(&class.created, 1)
+	return rv
+}
+
+// GetOriginalClass returns the class err had before Reclass recategorized
+// it, or nil if it wasn't reclassed. Reclassing more than once keeps the
+// first class.
+func GetOriginalClass(err error) *ErrorClass {
+	class, _ := GetData(err, originalClassKey).(*ErrorClass)
+	return class
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordedFrames is how many stack frames a Recorder keeps per error.
+const recordedFrames = 8
+
+// RecorderEntry is a summary of an error kept by a Recorder.
+type RecorderEntry struct {
+	Class   string    `json:"class"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	Stack   []string  `json:"stack,omitempty"`
+}
+
+// Recorder is a flight recorder: an in-memory ring buffer of the last few
+// errors it was given, so you can find out what errors happened recently
+// without relying on logs. Recorders are threadsafe. To record every error
+// caught by handlers, use
+//
+//	errors.OnCaught(recorder.Add)
+//
+// A Recorder is also an http.Handler serving its snapshot as JSON.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []RecorderEntry
+	next    int
+	full    bool
+}
+
+// NewRecorder makes a Recorder remembering the last size errors.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{entries: make([]RecorderEntry, size)}
+}
+
+// Add records the given error. nil errors are ignored.
+func (r *Recorder) Add(err error) {
+	if err == nil || len(r.entries) == 0 {
+		return
+	}
+	entry := RecorderEntry{
+		Class:   GetClass(err).String(),
+		Message: GetMessage(err),
+		Time:    time.Now()}
+	if stack := GetStack(err); stack != "" {
+		entry.Stack = strings.SplitN(stack, "\n", recordedFrames+1)
+		if len(entry.Stack) > recordedFrames {
+			entry.Stack = entry.Stack[:recordedFrames]
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Snapshot returns the recorded errors, oldest first.
+func (r *Recorder) Snapshot() []RecorderEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]RecorderEntry(nil), r.entries[:r.next]...)
+	}
+	rv := make([]RecorderEntry, 0, len(r.entries))
+	rv = append(rv, r.entries[r.next:]...)
+	return append(rv, r.entries[:r.next]...)
+}
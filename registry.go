@@ -0,0 +1,241 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	namespaceKey = GenSym()
+	dynamicKey   = GenSym()
+
+	// FrozenRegistryPanics controls what creating a class after
+	// FreezeRegistry does: panic with a ProgrammerError if true (the
+	// default), or just log the offending stack if false.
+	FrozenRegistryPanics = true
+
+	frozen uint32
+	// createdWhileFrozen and sharedClassID are assigned in init to break the
+	// initialization cycle between register and ProgrammerError.
+	createdWhileFrozen func(path string)
+	sharedClassID      func(path, other string)
+
+	registry struct {
+		mu      sync.Mutex
+		classes []*ErrorClass
+		byPath  map[string]*ErrorClass
+		// byID maps the ClassIDs of registered paths (and aliases) to
+		// the paths, to catch two paths hashing to the same ID.
+		byID map[uint32]string
+	}
+)
+
+// Namespace places the error class and its descendents in the given
+// namespace, typically the declaring package's module path without a version
+// suffix. Namespaced classes are interned by path: if two copies of a package
+// (say, two major versions in one dependency graph) both declare the same
+// class, errors from either copy will match classes from the other in Is and
+// Contains.
+func Namespace(ns string) ErrorOption {
+	return SetData(namespaceKey, ns)
+}
+
+// Path returns the class' registry path: the names of the class and its
+// ancestors joined by "/", prefixed by the class' namespace and a colon if it
+// has one.
+func (e *ErrorClass) Path() string {
+	if e == nil {
+		return ""
+	}
+	var names []string
+	for c := e; c != nil; c = c.parent {
+		names = append(names, c.name)
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	path := strings.Join(names, "/")
+	if ns, ok := e.data[namespaceKey].(string); ok && ns != "" {
+		return ns + ":" + path
+	}
+	return path
+}
+
+// canonical returns the class namespaced classes are interned to (or the
+// tombstone of a class of an unloaded plugin), or the receiver itself.
+func (e *ErrorClass) canonical() *ErrorClass {
+	if e == nil {
+		return nil
+	}
+	if canon := e.canon.Load(); canon != nil {
+		return canon
+	}
+	return e
+}
+
+// FreezeRegistry declares that all error classes have been created. Call it
+// once initialization is done; creating a class afterwards is a
+// ProgrammerError (see FrozenRegistryPanics), since classes created per
+// request leak memory and never match each other in Is. Classes declared
+// Dynamic, and their descendents, are exempt.
+func FreezeRegistry() {
+	atomic.StoreUint32(&frozen, 1)
+}
+
+func init() {
+	createdWhileFrozen = func(path string) {
+		if FrozenRegistryPanics {
+			panic(ProgrammerError.New(
+				"error class %q created after FreezeRegistry", path))
+		}
+		LogWithStack(fmt.Sprintf(
+			"error class %q created after FreezeRegistry", path))
+	}
+	sharedClassID = func(path, other string) {
+		panic(ProgrammerError.New(
+			"error class %q has the same ClassID as %q", path, other))
+	}
+}
+
+// Dynamic exempts the error class and its descendents from FreezeRegistry.
+// It's the escape hatch for code that legitimately creates classes after
+// startup, such as dynamically loaded plugins.
+func Dynamic() ErrorOption {
+	return SetData(dynamicKey, true)
+}
+
+// register adds a newly created class to the registry, interning it if it is
+// namespaced and its path is already taken. A class without a namespace
+// replaces any earlier class with its path, so LookupClass finds the newest.
+// It panics with a ProgrammerError if another path has the class' ClassID.
+func register(ec *ErrorClass) {
+	path := ec.Path()
+	if atomic.LoadUint32(&frozen) != 0 &&
+		!boolWrapper(ec.data[dynamicKey], false) {
+		createdWhileFrozen(path)
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if other := sharesID(path); other != "" && sharedClassID != nil {
+		sharedClassID(path, other)
+	}
+	if registry.byPath == nil {
+		registry.byPath = make(map[string]*ErrorClass)
+		registry.byID = make(map[uint32]string)
+	}
+	registry.byID[pathID(path)] = path
+	registry.classes = append(registry.classes, ec)
+	existing, exists := registry.byPath[path]
+	if _, ok := ec.data[namespaceKey].(string); ok && exists {
+		ec.canon.Store(existing.canonical())
+		return
+	}
+	registry.byPath[path] = ec
+}
+
+// sharesID returns the registered path, other than path itself, with the
+// same ClassID as path, or "" if there is none. registry.mu must be held.
+func sharesID(path string) string {
+	id := pathID(path)
+	for _, root := range []*ErrorClass{HierarchicalError, SystemError} {
+		if other := root.Path(); other != path && pathID(other) == id {
+			return other
+		}
+	}
+	if other, ok := registry.byID[id]; ok && other != path {
+		return other
+	}
+	return ""
+}
+
+// unregister removes the classes owned reports from the registry, with
+// their aliases and path history, frees their ancestry bits for reuse once
+// they are collected, and forgets their Adaptivestacks creation sites, for
+// Scope.Close and Plugin.Unload. It returns the classes removed.
+func unregister(owned func(*ErrorClass) bool) (removed []*ErrorClass) {
+	registry.mu.Lock()
+	kept := registry.classes[:0]
+	for _, class := range registry.classes {
+		if owned(class) {
+			removed = append(removed, class)
+		} else {
+			kept = append(kept, class)
+		}
+	}
+	for i := len(kept); i < len(registry.classes); i++ {
+		registry.classes[i] = nil
+	}
+	registry.classes = kept
+	for path, class := range registry.byPath {
+		if owned(class) {
+			delete(registry.byPath, path)
+		}
+	}
+	for path, class := range aliases {
+		if owned(class) {
+			delete(aliases, path)
+		}
+	}
+	for class := range history {
+		if owned(class) {
+			delete(history, class)
+		}
+	}
+	for id, path := range registry.byID {
+		if registry.byPath[path] == nil && aliases[path] == nil {
+			delete(registry.byID, id)
+		}
+	}
+	registry.mu.Unlock()
+
+	forgetSites(owned)
+	for _, class := range removed {
+		reclaimBit(class)
+	}
+	return removed
+}
+
+// Classes returns every error class created so far, starting with the root
+// classes, in creation order.
+func Classes() []*ErrorClass {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	rv := make([]*ErrorClass, 0, len(registry.classes)+2)
+	rv = append(rv, HierarchicalError, SystemError)
+	return append(rv, registry.classes...)
+}
+
+// LookupClass returns the class registered with the given path, or nil if
+// there is none. Namespaced paths find the first class registered with them,
+// which the others are interned to; other paths find the latest. See Path.
+// Former paths of classes moved by Rename and Reparent find the classes too,
+// unless a class now has that path.
+func LookupClass(path string) *ErrorClass {
+	for _, root := range []*ErrorClass{HierarchicalError, SystemError} {
+		if root.Path() == path {
+			return root
+		}
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if class, ok := registry.byPath[path]; ok {
+		return class
+	}
+	return aliases[path]
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync/atomic"
+)
+
+var (
+	scopeKey = GenSym()
+)
+
+// Scope is a registry scope. The classes created through it are registered
+// like any others until the scope is closed, and then the registry lets go
+// of them, so they can be garbage collected once nothing else refers to
+// them. So are their descendents, unless created with DisableInheritance.
+// It's for tests and plugins that create classes dynamically in long running
+// processes. Classes created any other way belong to the global scope, which
+// is permanent.
+type Scope struct {
+	closed uint32
+}
+
+// NewScope returns a new, open registry scope.
+func NewScope() *Scope {
+	return &Scope{}
+}
+
+// NewClass creates a class belonging to the scope under parent, which may be
+// a class of any scope (nil means HierarchicalError). The class and its
+// descendents are exempt from FreezeRegistry. Creating a class from a
+// closed scope is a ProgrammerError, and panics.
+func (s *Scope) NewClass(parent *ErrorClass, name string,
+	options ...ErrorOption) *ErrorClass {
+	if atomic.LoadUint32(&s.closed) != 0 {
+		panic(ProgrammerError.New(
+			"error class %q created from a closed scope", name))
+	}
+	options = append([]ErrorOption{SetData(scopeKey, s), Dynamic()},
+		options...)
+	return parent.NewClass(name, options...)
+}
+
+// Close removes the scope's classes from the registry, so Classes and
+// LookupClass no longer see them and the package keeps no references to
+// them. Errors of the classes, and the classes themselves, keep working for
+// as long as they are referenced. Closing a scope twice does nothing.
+func (s *Scope) Close() {
+	if !atomic.CompareAndSwapUint32(&s.closed, 0, 1) {
+		return
+	}
+	unregister(s.owns)
+}
+
+// owns returns whether the class belongs to the scope.
+func (s *Scope) owns(class *ErrorClass) bool {
+	owner, _ := class.data[scopeKey].(*Scope)
+	return owner == s
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+var (
+	routeKey = GenSym()
+)
+
+// Route holds alert routing labels, telling reporters and on-call
+// integrations who should hear about an error. Empty fields are unset.
+type Route struct {
+	Team    string
+	Service string
+	Channel string
+}
+
+// SetRoute returns an ErrorOption that attaches alert routing labels to the
+// error or error class and its descendents. Fields left empty in route are
+// inherited from the nearest ancestor class that sets them.
+func SetRoute(route Route) ErrorOption {
+	return SetData(routeKey, route)
+}
+
+// merge returns r with the non-empty fields of other applied over it.
+func (r Route) merge(other Route) Route {
+	if other.Team != "" {
+		r.Team = other.Team
+	}
+	if other.Service != "" {
+		r.Service = other.Service
+	}
+	if other.Channel != "" {
+		r.Channel = other.Channel
+	}
+	return r
+}
+
+// GetRoute returns the alert routing labels for the given error, or the zero
+// Route if none were set.
+func GetRoute(err error) Route {
+	cast, ok := err.(*Error)
+	if !ok {
+		return Route{}
+	}
+	var classes []*ErrorClass
+	if !boolWrapper(cast.data[disableInheritance], false) {
+		classes = cast.class.lineage()
+	}
+	var route Route
+	for i := len(classes) - 1; i >= 0; i-- {
+		if r, ok := classes[i].data[routeKey].(Route); ok {
+			route = route.merge(r)
+		}
+	}
+	if r, ok := cast.data[routeKey].(Route); ok {
+		route = route.merge(r)
+	}
+	return route
+}
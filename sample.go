@@ -0,0 +1,97 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	sampledKeys = struct {
+		sync.RWMutex
+		rates map[DataKey]float64
+	}{rates: make(map[DataKey]float64)}
+	// sampling is set once any key is sampled, to keep wrap cheap otherwise.
+	sampling uint32
+
+	// sampleFloat is swapped out by tests.
+	sampleFloat = rand.Float64
+)
+
+// DataSample stands in for a data value SampleData didn't keep. It records
+// enough to tell values apart and see how big they were.
+type DataSample struct {
+	// Size is the value's length in bytes, for strings and byte slices, or
+	// the length of its printed %v form otherwise.
+	Size int
+	// Hash is the hex SHA-256 of the same bytes.
+	Hash string
+}
+
+func (s DataSample) String() string {
+	return fmt.Sprintf("<sampled out: %d bytes, sha256 %s>", s.Size, s.Hash)
+}
+
+// SampleData makes errors keep values set under the key (with SetData on the
+// error, not the class) for only the given fraction of instances. The rest
+// keep a DataSample instead, which saves memory and report volume for keys
+// holding heavy values like payload dumps, while a representative fraction
+// of errors still carry the whole value. A rate of 1 or more keeps every
+// value again.
+func SampleData(key DataKey, rate float64) {
+	sampledKeys.Lock()
+	defer sampledKeys.Unlock()
+	if rate >= 1 {
+		delete(sampledKeys.rates, key)
+		return
+	}
+	sampledKeys.rates[key] = rate
+	atomic.StoreUint32(&sampling, 1)
+}
+
+// sampleData replaces the values of sampled keys in an error's data.
+func sampleData(data map[DataKey]interface{}) {
+	if atomic.LoadUint32(&sampling) == 0 {
+		return
+	}
+	sampledKeys.RLock()
+	defer sampledKeys.RUnlock()
+	for key, rate := range sampledKeys.rates {
+		val, ok := data[key]
+		if !ok || val == nil || sampleFloat() < rate {
+			continue
+		}
+		data[key] = summarize(val)
+	}
+}
+
+func summarize(val interface{}) DataSample {
+	var raw []byte
+	switch v := val.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		raw = []byte(fmt.Sprintf("%v", v))
+	}
+	sum := sha256.Sum256(raw)
+	return DataSample{Size: len(raw), Hash: hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"regexp"
+	"sync"
+)
+
+var (
+	publicKeys = struct {
+		sync.RWMutex
+		keys map[DataKey]bool
+	}{keys: make(map[DataKey]bool)}
+
+	// pathPattern matches absolute file paths with at least two components.
+	pathPattern = regexp.MustCompile(`/[^\s:'"()/]+(/[^\s:'"()/]+)+/?`)
+)
+
+// MarkPublicData declares that values stored under the given keys are safe to
+// send to external clients, so Sanitize keeps them.
+func MarkPublicData(keys ...DataKey) {
+	publicKeys.Lock()
+	defer publicKeys.Unlock()
+	for _, key := range keys {
+		publicKeys.keys[key] = true
+	}
+}
+
+// Sanitize returns a copy of err suitable for sending across a trust
+// boundary: stacks and exits are dropped, absolute file paths in messages
+// are replaced with "<path>", and only data under keys marked with
+// MarkPublicData is kept, besides the error's own UserFacing or Internal
+// marking and SetMessagePrefix template, so its PublicMessage and message
+// render as before. Class membership is preserved. The original error is
+// not modified.
+func Sanitize(err error) error {
+	if err == nil {
+		return nil
+	}
+	cast, ok := err.(*Error)
+	if !ok {
+		return &sanitizedError{
+			message: pathPattern.ReplaceAllString(err.Error(), "<path>"),
+			class:   GetClass(err)}
+	}
+	rv := &Error{err: Sanitize(cast.err), class: cast.class}
+	publicKeys.RLock()
+	defer publicKeys.RUnlock()
+	for key, val := range cast.data {
+		if publicKeys.keys[key] || key == userFacing || key == messagePrefix {
+			if rv.data == nil {
+				rv.data = make(map[DataKey]interface{})
+			}
+			rv.data[key] = val
+		}
+	}
+	return rv
+}
+
+// sanitizedError is a sanitized copy of an error that isn't an *Error. It
+// keeps the class the original was found to belong to, but not the
+// original, which may hold what was sanitized away.
+type sanitizedError struct {
+	message string
+	class   *ErrorClass
+}
+
+func (e *sanitizedError) Error() string { return e.message }
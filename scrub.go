@@ -0,0 +1,106 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"regexp"
+	"sync"
+)
+
+var (
+	scrubbersKey = GenSym()
+
+	scrubbers struct {
+		mu   sync.RWMutex
+		list []Scrubber
+	}
+)
+
+// A Scrubber removes sensitive information, such as emails or tokens, from
+// an error message.
+type Scrubber func(message string) string
+
+// RegexpScrubber returns a Scrubber replacing every match of re with
+// replacement, which may refer to submatches as in regexp.ReplaceAllString.
+func RegexpScrubber(re *regexp.Regexp, replacement string) Scrubber {
+	return func(message string) string {
+		return re.ReplaceAllString(message, replacement)
+	}
+}
+
+// RegisterScrubber adds a Scrubber applied to the messages of all errors.
+func RegisterScrubber(s Scrubber) {
+	scrubbers.mu.Lock()
+	defer scrubbers.mu.Unlock()
+	scrubbers.list = append(scrubbers.list, s)
+}
+
+// ScrubWith applies the given scrubbers to the messages of the error, or of
+// errors of the class and its descendents, after any global scrubbers.
+func ScrubWith(s ...Scrubber) ErrorOption {
+	return SetData(scrubbersKey, s)
+}
+
+// scrubbedError is a wrapped error whose message has been scrubbed. The
+// original is kept for inspection through Unwrap and FindType.
+type scrubbedError struct {
+	message string
+	err     error
+}
+
+func (e *scrubbedError) Error() string { return e.message }
+func (e *scrubbedError) Unwrap() error { return e.err }
+
+// scrub returns err with its message scrubbed, if there are any scrubbers
+// for the new error rv. Messages of *Errors were scrubbed when they were
+// created, so they're left alone.
+func scrub(rv *Error, err error) error {
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	classScrubbers, _ := rv.GetData(scrubbersKey).([]Scrubber)
+	scrubbers.mu.RLock()
+	global := len(scrubbers.list)
+	scrubbers.mu.RUnlock()
+	if global == 0 && len(classScrubbers) == 0 {
+		return err
+	}
+	if d, ok := err.(*deferredError); ok {
+		// keep the formatting deferred, and scrub once it happens.
+		return &deferredError{err: d, format: func() string {
+			return scrubMessage(d.Error(), classScrubbers)
+		}}
+	}
+	original := err.Error()
+	message := scrubMessage(original, classScrubbers)
+	if message == original {
+		return err
+	}
+	return &scrubbedError{message: message, err: err}
+}
+
+// scrubMessage applies the global scrubbers and then classScrubbers to
+// message.
+func scrubMessage(message string, classScrubbers []Scrubber) string {
+	scrubbers.mu.RLock()
+	defer scrubbers.mu.RUnlock()
+	for _, s := range scrubbers.list {
+		message = s(message)
+	}
+	for _, s := range classScrubbers {
+		message = s(message)
+	}
+	return message
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// StackDiff compares the stack an error was created on with the stack where
+// it was caught. Frames are innermost first, as in GetStack.
+type StackDiff struct {
+	// Thrown holds the frames only on the creation stack.
+	Thrown []string
+	// Caught holds the frames only on the catching stack.
+	Caught []string
+	// Common holds the frames both stacks share, innermost first.
+	Common []string
+	// Pivot names the function the two stacks diverge in, if they diverge
+	// at different lines of the same function (the outermost frames of
+	// Thrown and Caught). It's empty otherwise.
+	Pivot string
+}
+
+// DiffStack compares err's captured stack with the stack of its caller. It
+// returns the zero StackDiff if err has no captured stack.
+func DiffStack(err error) StackDiff {
+	cast, ok := err.(*Error)
+	if !ok || len(cast.stack) == 0 {
+		return StackDiff{}
+	}
+	var pcs [256]uintptr
+	caught := pcs[:runtime.Callers(2, pcs[:])]
+	thrown := cast.stack
+
+	common := 0
+	for common < len(thrown) && common < len(caught) &&
+		thrown[len(thrown)-1-common].pc == caught[len(caught)-1-common] {
+		common++
+	}
+	var diff StackDiff
+	if common < len(thrown) && common < len(caught) {
+		tf := runtime.FuncForPC(thrown[len(thrown)-1-common].pc)
+		cf := runtime.FuncForPC(caught[len(caught)-1-common])
+		if tf != nil && cf != nil && tf.Entry() == cf.Entry() {
+			diff.Pivot = tf.Name()
+		}
+	}
+	for _, f := range thrown[:len(thrown)-common] {
+		diff.Thrown = append(diff.Thrown, f.String())
+	}
+	for _, pc := range caught[:len(caught)-common] {
+		diff.Caught = append(diff.Caught, frame{pc: pc}.String())
+	}
+	for _, f := range thrown[len(thrown)-common:] {
+		diff.Common = append(diff.Common, f.String())
+	}
+	return diff
+}
+
+// String renders the diff as a one line summary, "thrown at A..B, caught at
+// C..B", where A and C are the innermost frames of each stack and B is where
+// they diverge: the Pivot, or else the innermost common frame.
+func (d StackDiff) String() string {
+	from := d.Pivot
+	if from == "" && len(d.Common) > 0 {
+		from = d.Common[0]
+	}
+	at := func(frames []string) string {
+		switch {
+		case len(frames) == 0 && from == "":
+			return "unknown"
+		case len(frames) == 0:
+			return from
+		case from == "":
+			return frames[0]
+		}
+		return frames[0] + ".." + from
+	}
+	return fmt.Sprintf("thrown at %s, caught at %s", at(d.Thrown), at(d.Caught))
+}
+
+// Detail renders the divergent frames of both stacks in full, followed by
+// the pivot and the innermost common frame, instead of two complete stack
+// dumps.
+func (d StackDiff) Detail() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "thrown at:\n")
+	for _, f := range d.Thrown {
+		fmt.Fprintf(&b, "  %s\n", f)
+	}
+	fmt.Fprintf(&b, "caught at:\n")
+	for _, f := range d.Caught {
+		fmt.Fprintf(&b, "  %s\n", f)
+	}
+	if d.Pivot != "" {
+		fmt.Fprintf(&b, "both from:\n  %s\n", d.Pivot)
+	}
+	if len(d.Common) > 0 {
+		fmt.Fprintf(&b, "common frames (%d), innermost:\n  %s\n",
+			len(d.Common), d.Common[0])
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"sync"
+)
+
+// symbolizeQueueSize bounds how many errors can wait for the background
+// symbolizer before SymbolizeAsync starts dropping them.
+const symbolizeQueueSize = 256
+
+var symbolizer struct {
+	once  sync.Once
+	queue chan *Error
+}
+
+// SymbolizeAsync asks a background worker to resolve the error's captured
+// stack into text, memoizing the result on the error so later rendering is
+// cheap. Creating an error only ever captures program counters; resolving
+// them into function names and lines is the expensive part, and otherwise
+// happens the first time the stack is rendered. If the worker is backed up
+// the request is dropped and rendering happens on demand as usual. Errors
+// from Pooled classes are never queued, since they may be released and
+// reused while they wait.
+func SymbolizeAsync(err error) {
+	cast, ok := err.(*Error)
+	if !ok || len(cast.stack) == 0 || cast.pooled {
+		return
+	}
+	// a deferred stack is the one the error escapes through, so it's
+	// captured here, not on the worker.
+	cast.escape()
+	symbolizer.once.Do(func() {
+		symbolizer.queue = make(chan *Error, symbolizeQueueSize)
+		go func() {
+			for e := range symbolizer.queue {
+				e.Stack()
+			}
+		}()
+	})
+	select {
+	case symbolizer.queue <- cast:
+	default:
+	}
+}
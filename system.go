@@ -0,0 +1,120 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo && !errors_tiny
+
+package errors
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+)
+
+// stackSupport is whether the runtime can capture stacks; see
+// system_tiny.go.
+const stackSupport = true
+
+// systemSentinels maps well known standard library error values to classes.
+// It is searched in order, so more specific values come first.
+var systemSentinels = []struct {
+	err   error
+	class *ErrorClass
+}{
+	{io.EOF, EOF},
+	{io.ErrUnexpectedEOF, UnexpectedEOFError},
+	{io.ErrClosedPipe, ClosedPipeError},
+	{io.ErrNoProgress, NoProgressError},
+	{io.ErrShortBuffer, ShortBufferError},
+	{io.ErrShortWrite, ShortWriteError},
+	{net.ErrClosed, NetClosedError},
+	{context.Canceled, ContextCanceledError},
+	{context.DeadlineExceeded, ContextDeadlineError},
+	{os.ErrDeadlineExceeded, DeadlineExceeded},
+	{os.ErrProcessDone, ProcessDoneError},
+	{os.ErrNotExist, NotExistError},
+	{os.ErrExist, ExistError},
+	{os.ErrPermission, PermissionError},
+	{os.ErrClosed, ClosedFileError},
+}
+
+// stdClass maps standard library errors to the classes declared for them.
+func stdClass(err error) *ErrorClass {
+	for _, sentinel := range systemSentinels {
+		if err == sentinel.err {
+			return sentinel.class
+		}
+	}
+	switch err.(type) {
+	case *os.SyscallError:
+		return SyscallError
+	case *os.PathError:
+		return PathError
+	case *os.LinkError:
+		return LinkError
+	case syscall.Errno:
+		return ErrnoError
+	case net.UnknownNetworkError:
+		return UnknownNetworkError
+	case *net.AddrError:
+		return AddrError
+	case net.InvalidAddrError:
+		return InvalidAddrError
+	case *net.OpError:
+		if class := netTimeoutClass(err.(*net.OpError)); class != nil {
+			return class
+		}
+		return NetOpError
+	case *net.ParseError:
+		return NetParseError
+	case *net.DNSError:
+		return DNSError
+	case *net.DNSConfigError:
+		return DNSConfigError
+	case *url.Error:
+		if err.(*url.Error).Timeout() {
+			return RequestTimeoutError
+		}
+		return NetworkError
+	case net.Error:
+		if err.(net.Error).Timeout() {
+			return NetTimeoutError
+		}
+		return NetworkError
+	case interface{ Timeout() bool }:
+		if err.(interface{ Timeout() bool }).Timeout() {
+			return TimeoutError
+		}
+		return SystemError
+	default:
+		return SystemError
+	}
+}
+
+// netTimeoutClass classifies a timed out dial or read.
+func netTimeoutClass(err *net.OpError) *ErrorClass {
+	if !err.Timeout() {
+		return nil
+	}
+	switch err.Op {
+	case "dial":
+		return DialTimeoutError
+	case "read":
+		return ReadTimeoutError
+	}
+	return NetOpTimeoutError
+}
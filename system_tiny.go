@@ -0,0 +1,59 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tinygo || errors_tiny
+
+package errors
+
+import (
+	"context"
+	"io"
+)
+
+// The reduced build for TinyGo and WebAssembly clients (selected by the
+// tinygo or errors_tiny build tags) keeps the whole class taxonomy, so errors
+// classify the same way on both sides, but drops the package's own use of
+// net, net/http, runtime/pprof and syscall (so only io and context errors are
+// recognized), the HTTP debug handlers, goroutine labels and stack capture.
+// It still imports os (for the journal), text/template (for typed classes)
+// and go/format (for GenerateClassRefs), which bring in net/url and syscall
+// indirectly.
+const stackSupport = false
+
+var systemSentinels = []struct {
+	err   error
+	class *ErrorClass
+}{
+	{io.EOF, EOF},
+	{io.ErrUnexpectedEOF, UnexpectedEOFError},
+	{io.ErrClosedPipe, ClosedPipeError},
+	{io.ErrNoProgress, NoProgressError},
+	{io.ErrShortBuffer, ShortBufferError},
+	{io.ErrShortWrite, ShortWriteError},
+	{context.Canceled, ContextCanceledError},
+	{context.DeadlineExceeded, ContextDeadlineError},
+}
+
+// stdClass maps standard library errors to the classes declared for them.
+func stdClass(err error) *ErrorClass {
+	for _, sentinel := range systemSentinels {
+		if err == sentinel.err {
+			return sentinel.class
+		}
+	}
+	if timeout, ok := err.(interface{ Timeout() bool }); ok && timeout.Timeout() {
+		return TimeoutError
+	}
+	return SystemError
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"time"
+)
+
+var (
+	// timeoutPolicy is shared by every timeout class, including the ones
+	// outside the TimeoutError family, so retry loops and HTTP handlers
+	// treat all timeouts alike.
+	timeoutPolicy = Policy{
+		Retryable:  PolicyYes,
+		HTTPStatus: 504, // Gateway Timeout
+	}
+
+	timeoutLimitKey = GenSym()
+
+	// Timeouts of network operations stay in the NetworkError tree, so
+	// checks for NetOpError and NetworkError keep matching them. Timed out
+	// *net.OpErrors are classified into NetOpTimeoutError, or its
+	// DialTimeoutError and ReadTimeoutError, and timeouts from net/http
+	// clients into RequestTimeoutError.
+	NetOpTimeoutError = NetOpError.NewClass("Network Op Timeout Error",
+		SetPolicy(timeoutPolicy))
+	DialTimeoutError    = NetOpTimeoutError.NewClass("Dial Timeout Error")
+	ReadTimeoutError    = NetOpTimeoutError.NewClass("Read Timeout Error")
+	RequestTimeoutError = NetworkError.NewClass("Request Timeout Error",
+		SetPolicy(timeoutPolicy))
+
+	// LockTimeoutError is in the TimeoutError family, for code timing out
+	// waiting on locks.
+	LockTimeoutError = TimeoutError.NewClass("Lock Timeout Error")
+)
+
+// TimeoutAfter returns an ErrorOption recording the configured limit an
+// operation timed out against, and how long it had run (for GetDuration):
+//
+//	LockTimeoutError.NewWith("lock on "+name, errors.TimeoutAfter(limit, elapsed))
+func TimeoutAfter(limit, elapsed time.Duration) ErrorOption {
+	return func(data map[DataKey]interface{}) {
+		data[timeoutLimitKey] = limit
+		data[durationKey] = elapsed
+	}
+}
+
+// GetTimeoutLimit returns the limit recorded by TimeoutAfter, and whether
+// there was one.
+func GetTimeoutLimit(err error) (time.Duration, bool) {
+	limit, ok := GetData(err, timeoutLimitKey).(time.Duration)
+	return limit, ok
+}
+
+// IsTimeout reports whether err, or an error it wraps, is any kind of
+// timeout: the TimeoutError family, NetTimeoutError, NetOpTimeoutError,
+// RequestTimeoutError or ContextDeadlineError.
+func IsTimeout(err error) bool {
+	return walk(err, func(layer error) bool {
+		class := GetClass(layer)
+		return class != nil && (class.Is(TimeoutError) ||
+			class.Is(NetTimeoutError) || class.Is(NetOpTimeoutError) ||
+			class.Is(RequestTimeoutError) ||
+			class.Is(ContextDeadlineError))
+	})
+}
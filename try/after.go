@@ -0,0 +1,72 @@
+package try
+
+import (
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	Summarizes how a plan went, for `After` functions.  A plan whose main
+	function returned normally has the zero `Outcome`.
+*/
+type Outcome struct {
+	// The error given to the `Catch` or `CatchAll` handler that ran, or nil
+	// if none ran.
+	Handled error
+	// The class of the `Catch` that ran, or nil if none or a `CatchAll` ran.
+	Matched *errors.ErrorClass
+	// True if a handler ran and nothing escaped the plan.
+	Consumed bool
+	// The error propagating out of the plan (whether no handler matched,
+	// a handler rethrew, or a `Finally` block panicked), or nil.  Non-error
+	// panic values are wrapped in an `UnknownPanicError`.
+	Escaping error
+}
+
+/*
+	Adds a function run after everything else in the plan, `Finally` blocks
+	included, with a summary of how the plan went.  This suits work like
+	committing metrics or releasing a lock after everything else, which
+	would otherwise need state shared between `Catch` and `Finally` closures.
+
+	After functions run in the order they were added.  If one panics, the
+	panic is handled like a panicking `Finally` block's.
+*/
+func (p *Plan) After(f func(outcome Outcome)) *Plan {
+	p.after = append(p.after, f)
+	return p
+}
+
+// runAfter is deferred by `Done` (before `handle`, so it runs last) for
+// plans with `After` functions.
+func (p *Plan) runAfter() {
+	rec := recover()
+	outcome := Outcome{Handled: p.handled, Consumed: p.handled != nil && rec == nil}
+	if p.matched != nil {
+		outcome.Matched = p.matched.match
+	}
+	if rec != nil {
+		if err, ok := rec.(error); ok {
+			outcome.Escaping = err
+		} else {
+			outcome.Escaping = unknownPanic(rec)
+		}
+	}
+	release := p.release
+	p.matched, p.handled, p.release = nil, nil, nil
+	p.callAfter(outcome, rec)
+	release.Release()
+	if rec != nil {
+		panic(rec)
+	}
+}
+
+func (p *Plan) callAfter(outcome Outcome, rec interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(handlerPanic(func() string { return "After" }, r, rec, nil))
+		}
+	}()
+	for _, f := range p.after {
+		f(outcome)
+	}
+}
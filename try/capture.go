@@ -0,0 +1,133 @@
+package try
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+// The spacemonkey error key holding the log lines captured by `CaptureLogs`.
+var CapturedLogsKey = errors.GenSym()
+
+// captures tracks, per goroutine, the log rings of the capturing plans that
+// are currently running.
+var captures = struct {
+	sync.Mutex
+	rings map[int64][]*logRing
+}{rings: make(map[int64][]*logRing)}
+
+// logRing keeps the last few lines logged while a plan ran.
+type logRing struct {
+	goid  int64
+	lines []string
+	next  int
+	full  bool
+}
+
+func (r *logRing) add(line string) {
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// contents returns the captured lines, oldest first.
+func (r *logRing) contents() []string {
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// captureWriter sits in front of the log package's output and copies lines
+// logged on goroutines with capturing plans into their rings.
+type captureWriter struct {
+	out io.Writer
+}
+
+func (w captureWriter) Write(p []byte) (int, error) {
+	captures.Lock()
+	if len(captures.rings) > 0 {
+		rings := captures.rings[goid()]
+		for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+			for _, ring := range rings {
+				ring.add(line)
+			}
+		}
+	}
+	captures.Unlock()
+	return w.out.Write(p)
+}
+
+/*
+	Has the plan keep the last `n` lines logged while it runs, and attach
+	them to spacemonkey errors passing through it under `CapturedLogsKey`
+	(see `CapturedLogs`), so a report about a failed operation includes
+	what it logged on the way to failing.  Errors that already carry
+	captured logs from an inner plan keep those.
+
+	Lines are captured from the standard `log` package (which is where
+	`errors.LogMethod` writes by default), for the goroutine running the
+	plan.  While any capturing plan runs, a writer sits in front of
+	`log.Writer()`, and the output is put back when the last one finishes.
+	Calling `log.SetOutput` while one runs stops capture until the next one
+	starts, and the new output is left alone.
+*/
+func (p *Plan) CaptureLogs(n int) *Plan {
+	p.captureLogs = n
+	return p
+}
+
+// Returns the log lines captured by `CaptureLogs` for an error, oldest first.
+func CapturedLogs(err error) []string {
+	lines, _ := errors.GetData(err, CapturedLogsKey).([]string)
+	return lines
+}
+
+func (p *Plan) startCapture() {
+	ring := &logRing{goid: goid(), lines: make([]string, p.captureLogs)}
+	captures.Lock()
+	defer captures.Unlock()
+	if _, ok := log.Writer().(captureWriter); !ok {
+		log.SetOutput(captureWriter{out: log.Writer()})
+	}
+	captures.rings[ring.goid] = append(captures.rings[ring.goid], ring)
+	p.capture = ring
+}
+
+func (p *Plan) stopCapture() {
+	ring := p.capture
+	captures.Lock()
+	defer captures.Unlock()
+	rings := captures.rings[ring.goid]
+	for i := range rings {
+		if rings[i] == ring {
+			rings = append(rings[:i], rings[i+1:]...)
+			break
+		}
+	}
+	if len(rings) == 0 {
+		delete(captures.rings, ring.goid)
+	} else {
+		captures.rings[ring.goid] = rings
+	}
+	if len(captures.rings) == 0 {
+		if w, ok := log.Writer().(captureWriter); ok {
+			log.SetOutput(w.out)
+		}
+	}
+}
+
+// attachCapture attaches the plan's captured lines to rec, if it's a
+// spacemonkey error without captured lines of its own.
+func (p *Plan) attachCapture(rec interface{}) {
+	err, ok := rec.(*errors.Error)
+	if !ok || err.GetData(CapturedLogsKey) != nil {
+		return
+	}
+	errors.Annotate(err, CapturedLogsKey, p.capture.contents())
+}
@@ -0,0 +1,56 @@
+package try
+
+import (
+	"context"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	Like `Do`, but the plan carries a context, which is passed to the main
+	function and to handlers added with `CatchCtx`, `CatchAllCtx` and
+	`FinallyCtx`.  The context may well be canceled by the time a handler
+	runs (that's often why the plan failed), so handlers making cleanup
+	calls can respect or deliberately detach from the cancellation.
+*/
+func DoCtx(ctx context.Context, f func(ctx context.Context)) *Plan {
+	p := Do(func() { f(ctx) })
+	p.ctx = ctx
+	return p
+}
+
+/*
+	Returns the context given to `DoCtx`, or `context.Background()` for plans
+	made by `Do`.
+*/
+func (p *Plan) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// Like `Catch`, but the handler also receives the plan's context.
+func (p *Plan) CatchCtx(kind *errors.ErrorClass, handler func(ctx context.Context, err *errors.Error)) *Plan {
+	p.Catch(kind, func(err *errors.Error) {
+		handler(p.Context(), err)
+	})
+	p.catch[len(p.catch)-1].orig = handler
+	return p
+}
+
+// Like `CatchAll`, but the handler also receives the plan's context.
+func (p *Plan) CatchAllCtx(handler func(ctx context.Context, err error)) *Plan {
+	p.CatchAll(func(err error) {
+		handler(p.Context(), err)
+	})
+	p.catch[len(p.catch)-1].orig = handler
+	return p
+}
+
+// Like `Finally`, but the block also receives the plan's context.
+func (p *Plan) FinallyCtx(f func(ctx context.Context)) *Plan {
+	return p.Finally(func() {
+		f(p.Context())
+	})
+}
@@ -0,0 +1,186 @@
+package try
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+const (
+	// diagnoseSamples is how many errors a catch's plan must have raised
+	// before the catch can be reported unreachable.
+	diagnoseSamples = 8
+	// maxDiagnosedSites bounds how many catches `DiagnoseCatches` tracks.
+	maxDiagnosedSites = 4096
+)
+
+var (
+	/*
+		Set to have plans check their typed catches against what their
+		blocks actually raise, and call `OnUnreachableCatch` for each `Catch`
+		whose class is from a different hierarchy than everything the block
+		has raised, once it has raised a handful of errors, or, for plans
+		given `Raises`, unrelated to every class declared.  A typo'd or
+		stale class reference otherwise never fires, silently.  It costs a
+		stack walk per catch added, and a lock and a map update per catch
+		for each caught panic.
+
+		Hierarchies are the classes directly under the package roots
+		(`errors.HierarchicalError` and `errors.SystemError`) and everything
+		descending from them, and a catch of a root covers every hierarchy
+		under it.  Catches are told apart by the line that added them, so
+		plans made from the same function, as `DoCtx`'s are, still get
+		their own diagnostics, and each is reported once.  Catches added
+		while it isn't set are never reported.
+	*/
+	DiagnoseCatches = false
+
+	/*
+		Called with a description of each unreachable catch found when
+		`DiagnoseCatches` is set, naming the class, the line the catch was
+		added on and, for named plans, the plan.  The default logs it with
+		`errors.LogMethod`, since a dead handler is a bug to fix rather than
+		a failure of the running operation; a test suite can replace it to
+		collect the descriptions and fail on any.
+	*/
+	OnUnreachableCatch = func(diagnostic string) {
+		errors.LogMethod("%s", diagnostic)
+	}
+
+	diagnostics struct {
+		mu    sync.Mutex
+		sites map[catchSite]*siteStats
+	}
+
+	// tryPrefix is the prefix of the names of this package's functions.
+	tryPrefix = strings.TrimSuffix(
+		runtime.FuncForPC(reflect.ValueOf(Do).Pointer()).Name(), "Do")
+)
+
+// catchSite identifies a typed catch: the line that added it, and its class.
+type catchSite struct {
+	pc    uintptr
+	class *errors.ErrorClass
+}
+
+// siteStats is what `DiagnoseCatches` has seen of the plans of one catch.
+type siteStats struct {
+	raised   int
+	families map[*errors.ErrorClass]bool
+	reported bool
+}
+
+// callerPC returns the pc of the first caller outside this package, for
+// telling catches apart under `DiagnoseCatches`, or 0 if it isn't set.
+func callerPC() uintptr {
+	if !DiagnoseCatches {
+		return 0
+	}
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, tryPrefix) {
+			return frame.PC
+		}
+		if !more {
+			return 0
+		}
+	}
+}
+
+// statsFor returns the stats of the catch, creating them if needed, or nil
+// if the catch wasn't added under `DiagnoseCatches` or too many catches are
+// tracked already.  diagnostics.mu must be held.
+func statsFor(catch check) *siteStats {
+	if catch.site == 0 || catch.match == nil {
+		return nil
+	}
+	site := catchSite{pc: catch.site, class: catch.match}
+	if diagnostics.sites == nil {
+		diagnostics.sites = make(map[catchSite]*siteStats)
+	}
+	stats := diagnostics.sites[site]
+	if stats == nil {
+		if len(diagnostics.sites) >= maxDiagnosedSites {
+			return nil
+		}
+		stats = &siteStats{families: make(map[*errors.ErrorClass]bool)}
+		diagnostics.sites[site] = stats
+	}
+	return stats
+}
+
+// covers returns whether any of the families seen is in the hierarchy of
+// match, which for a root class is any family under it.
+func (s *siteStats) covers(match *errors.ErrorClass) bool {
+	if s.families[family(match)] {
+		return true
+	}
+	for seen := range s.families {
+		if seen.Is(match) {
+			return true
+		}
+	}
+	return false
+}
+
+// family returns the class directly under a root that c descends from, or
+// the root itself.
+func family(c *errors.ErrorClass) *errors.ErrorClass {
+	for c.Parent() != nil && c.Parent().Parent() != nil {
+		c = c.Parent()
+	}
+	return c
+}
+
+// diagnose records the families of err's layers against the plan's typed
+// catches, and reports those that belong to none of the families seen.
+func (p *Plan) diagnose(err *errors.Error) {
+	var reports []string
+
+	diagnostics.mu.Lock()
+	for _, catch := range p.catch {
+		stats := statsFor(catch)
+		if stats == nil {
+			continue
+		}
+		stats.raised++
+		for layer := err; layer != nil; {
+			stats.families[family(layer.Class())] = true
+			layer, _ = layer.WrappedErr().(*errors.Error)
+		}
+		if stats.reported || stats.raised < diagnoseSamples ||
+			stats.covers(catch.match) {
+			continue
+		}
+		stats.reported = true
+		reports = append(reports, fmt.Sprintf(
+			"try: Catch(%s) %s can't match anything its block has raised: "+
+				"none of the %d errors raised so far were in the %s hierarchy",
+			catch.match.Path(), p.siteName(catch.site), stats.raised,
+			family(catch.match).Path()))
+	}
+	diagnostics.mu.Unlock()
+
+	for _, report := range reports {
+		OnUnreachableCatch(report)
+	}
+}
+
+// siteName describes where a catch was added for diagnostics.
+func (p *Plan) siteName(site uintptr) string {
+	where := "in plan"
+	if fn := runtime.FuncForPC(site); fn != nil {
+		file, line := fn.FileLine(site)
+		where = fmt.Sprintf("at %s:%d", file, line)
+	}
+	if p.name != "" {
+		where += fmt.Sprintf(" in plan '%s'", p.name)
+	}
+	return where
+}
@@ -0,0 +1,7 @@
+//go:build !go1.24
+
+package try
+
+// This package uses runtime.AddCleanup and iterators, so it needs Go 1.24 or
+// later.  Older toolchains stop here, naming the requirement.
+var _ = requires_go1_24_or_later
@@ -0,0 +1,74 @@
+package try
+
+import (
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	Starts `f` in a new goroutine, and returns a function that waits for it
+	to return and then re-raises its panic, if any, in the calling goroutine.
+
+	A plan can only catch panics from its own goroutine: a panic in a
+	goroutine started with a plain `go` statement inside a plan's main
+	function bypasses the plan entirely and crashes the program.  Calling the
+	returned function from the main function brings the panic back where the
+	plan's handlers can catch it:
+
+		try.Do(func() {
+			wait := try.Go(fetch)
+			process()
+			wait()
+		}).Catch(FetchError, handle).Done()
+
+	If the returned function is never called, the panic is silently lost, so
+	always call it.  For several goroutines, see `RunScope`.
+*/
+func Go(f func()) (wait func()) {
+	done := make(chan error, 1)
+	go func() {
+		var failure error
+		defer func() { done <- failure }()
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if errors.IsFatalRuntimePanic(rec) && !ConsumeFatalRuntime {
+				panic(rec)
+			}
+			err, ok := rec.(error)
+			if !ok {
+				err = unknownPanic(rec)
+			}
+			failure = errors.RecordPanicOrigin(err)
+		}()
+		f()
+	}()
+	return func() {
+		if failure := <-done; failure != nil {
+			Repanic(failure)
+		}
+	}
+}
+
+/*
+	Asserts that a plan's main function is running on the current goroutine,
+	so a panic here will reach its handlers.  Call it in helpers that rely
+	on an enclosing plan to catch their panics, to find the ones mistakenly
+	run on a goroutine of their own.
+
+	Panics with a `ProgrammerError` if no plan is running on this goroutine.
+	It requires `TrackNesting`, and does nothing when it's off, so the
+	assertions can stay in place and be enabled for debugging.
+*/
+func MustSameGoroutine() {
+	if !TrackNesting {
+		return
+	}
+	running.Lock()
+	stack := running.plans[goid()]
+	running.Unlock()
+	if len(stack) == 0 {
+		panic(errors.ProgrammerError.New("no try plan is running on this goroutine; its panics can't be caught"))
+	}
+}
@@ -0,0 +1,142 @@
+package try
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	/*
+		Panic type when a `Catch`, `CatchAll` or `Finally` handler panics with
+		something other than a rethrow, so outer layers can tell a bug in an
+		error handler from the business failure it was handling.  It wraps
+		the handler's panic (non-error values in an `UnknownPanicError`), and
+		carries `HandlerKey` and `InFlightKey`.
+
+		Rethrowing the in-flight error (by panicking with it, its original
+		value, or an error wrapping it), returning an error from a `CatchErr`
+		or `CatchAllErr` handler, and fatal panics are not handler bugs, and
+		propagate as they are.
+	*/
+	HandlerError = errors.NewClass("Handler Error", errors.NoCaptureStack())
+
+	// The spacemonkey error key to get a description of the handler that
+	// panicked out of a `HandlerError`, such as "Catch(Fruit Error) at
+	// main.main.func2:main.go:20".
+	HandlerKey = errors.GenSym()
+
+	// The spacemonkey error key to get the error (or non-error panic value)
+	// a handler was handling when it panicked out of a `HandlerError`.  It's
+	// nil for `Finally` blocks that panicked on the success path.
+	InFlightKey = errors.GenSym()
+)
+
+// deliberate carries an error raised on purpose by a handler, such as one
+// returned from a `CatchErr` handler, past the handler-bug detection.
+type deliberate struct {
+	value interface{}
+}
+
+// caught reports that the check's handler consumed err, in the plan's
+// context if it has one.
+func (c *check) caught(ctx context.Context, err error) {
+	var handler string
+	if AuditHandlers {
+		handler = c.describe()
+	}
+	if ctx != nil {
+		errors.CaughtCtx(ctx, err, handler)
+		return
+	}
+	errors.CaughtBy(err, handler)
+}
+
+// describe names a check's handler for `HandlerKey`.
+func (c *check) describe() string {
+	handler := c.orig
+	if handler == nil {
+		handler = c.handler
+		if c.catchAll() {
+			handler = c.anyhandler
+		}
+	}
+	if c.catchAll() {
+		return fmt.Sprintf("CatchAll at %s", funcLocation(handler))
+	}
+	return fmt.Sprintf("Catch(%s) at %s", c.match, funcLocation(handler))
+}
+
+// handlerPanic decides what a handler's panic `r` propagates as, given the
+// original panic value `rec` and the error the handler was given.
+func handlerPanic(describe func() string, r, rec interface{}, handled error) interface{} {
+	if d, ok := r.(deliberate); ok {
+		return d.value
+	}
+	if samePanic(r, rec) || errors.IsFatalRuntimePanic(r) {
+		return r
+	}
+	err, ok := r.(error)
+	if ok && (errors.IsFatal(err) || HandlerError.Contains(err) || wraps(err, rec) || (handled != nil && wraps(err, handled))) {
+		return r
+	}
+	if !ok {
+		err = unknownPanic(r)
+	}
+	inFlight := rec
+	if handled != nil {
+		inFlight = handled
+	}
+	return HandlerError.Wrap(errors.RecordPanicOrigin(err),
+		errors.SetData(HandlerKey, describe()),
+		errors.SetData(InFlightKey, inFlight))
+}
+
+// samePanic compares panic values, which may not be comparable.
+func samePanic(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta.Comparable() {
+		return a == b
+	}
+	switch ta.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Func:
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return false
+}
+
+// wraps reports whether err is, or wraps, the panic value target.
+func wraps(err error, target interface{}) bool {
+	for err != nil {
+		if samePanic(err, target) {
+			return true
+		}
+		switch cast := err.(type) {
+		case *errors.Error:
+			err = cast.WrappedErr()
+		case interface{ Unwrap() error }:
+			err = cast.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// recoverFinally is deferred around `Finally` blocks to class their panics
+// as `HandlerError`s.
+func (p *Plan) recoverFinally(rec interface{}) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panic(handlerPanic(func() string { return "Finally" }, r, rec, nil))
+}
@@ -0,0 +1,86 @@
+package try
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	// The spacemonkey error key marking errors raised by `InjectFault`.  Its
+	// value is the name of the plan the fault was injected into.
+	InjectedKey = errors.GenSym()
+
+	faults struct {
+		sync.RWMutex
+		count int32
+		list  []*fault
+	}
+)
+
+type fault struct {
+	plan        string
+	class       *errors.ErrorClass
+	probability float64
+}
+
+/*
+	Makes plans named `plan` (see `DoNamed`) fail with a new error of
+	`class`, with the given probability, instead of running their main
+	function.  The error is raised just as if the main function had panicked
+	with it, so tests and chaos tooling can check that handlers cover the
+	failures they should and that `Finally` blocks hold up.  An empty `plan`
+	matches every plan, named or not.  Injected errors carry `InjectedKey`.
+
+	Returns a function removing the fault.  Faults are checked in the order
+	they were injected, and the first that fires wins.  While none are
+	injected, plans pay only an atomic load for the feature.
+*/
+func InjectFault(plan string, class *errors.ErrorClass, probability float64) (remove func()) {
+	f := &fault{plan: plan, class: class, probability: probability}
+	faults.Lock()
+	faults.list = append(faults.list, f)
+	atomic.AddInt32(&faults.count, 1)
+	faults.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			faults.Lock()
+			defer faults.Unlock()
+			for i, other := range faults.list {
+				if other == f {
+					faults.list = append(faults.list[:i:i], faults.list[i+1:]...)
+					atomic.AddInt32(&faults.count, -1)
+					return
+				}
+			}
+		})
+	}
+}
+
+// injectedFault returns the error to raise instead of running the plan's
+// main function, if a fault fires.
+func (p *Plan) injectedFault() error {
+	if atomic.LoadInt32(&faults.count) == 0 {
+		return nil
+	}
+	faults.RLock()
+	defer faults.RUnlock()
+	for _, f := range faults.list {
+		if f.plan != "" && f.plan != p.name {
+			continue
+		}
+		if f.probability < 1 && rand.Float64() >= f.probability {
+			continue
+		}
+		message := "fault injected"
+		if p.name != "" {
+			message = fmt.Sprintf("fault injected into plan '%s'", p.name)
+		}
+		return f.class.NewWith(message, errors.SetData(InjectedKey, p.name))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package try
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	Set `TrackNesting` to have plans keep track of which plans are running on
+	each goroutine, which `DeferToParent` requires.  It's off by default since
+	it costs a few allocations in every `Done()`.
+*/
+var TrackNesting = false
+
+// running tracks, per goroutine, the stack of plans whose main functions are
+// currently executing.
+var running = struct {
+	sync.Mutex
+	plans map[int64][]*Plan
+}{plans: make(map[int64][]*Plan)}
+
+// goid returns the id of the current goroutine, parsed out of the header
+// line of its stack trace.
+func goid() int64 {
+	var buf [64]byte
+	line := buf[:runtime.Stack(buf[:], false)]
+	line = bytes.TrimPrefix(line, []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		line = line[:i]
+	}
+	id, _ := strconv.ParseInt(string(line), 10, 64)
+	return id
+}
+
+func pushPlan(p *Plan) {
+	id := goid()
+	running.Lock()
+	defer running.Unlock()
+	running.plans[id] = append(running.plans[id], p)
+}
+
+func popPlan() {
+	id := goid()
+	running.Lock()
+	defer running.Unlock()
+	stack := running.plans[id]
+	if len(stack) <= 1 {
+		delete(running.plans, id)
+		return
+	}
+	running.plans[id] = stack[:len(stack)-1]
+}
+
+/*
+	Schedules `f` to run with the `Finally` blocks of the plan enclosing the
+	innermost plan currently running on this goroutine.
+
+	This lets a helper that does its work in its own `try.Do` block schedule
+	cleanup that must outlive that block, but still happen when the outer
+	operation completes.  Panics with a `ProgrammerError` if there is no such
+	enclosing plan, or if `TrackNesting` is off.
+*/
+func DeferToParent(f func()) {
+	id := goid()
+	running.Lock()
+	stack := running.plans[id]
+	running.Unlock()
+	if !TrackNesting {
+		panic(errors.ProgrammerError.New("try.DeferToParent requires try.TrackNesting"))
+	}
+	if len(stack) < 2 {
+		panic(errors.ProgrammerError.New("try.DeferToParent called without an enclosing plan"))
+	}
+	stack[len(stack)-2].Finally(f)
+}
@@ -0,0 +1,57 @@
+package try
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var panicStringers = struct {
+	sync.RWMutex
+	byType map[reflect.Type]func(interface{}) string
+}{byType: make(map[reflect.Type]func(interface{}) string)}
+
+/*
+	Registers the function used to build the message of the
+	`UnknownPanicError` made when a value of type `T` is panicked.  The value
+	itself is always kept intact under `OriginalErrorKey`.
+
+	Without a registered stringer, maps, slices, funcs, channels and unsafe
+	pointers are described by their type name and pointer identity, like
+	"map[string]int at 0xc000012345", rather than formatted with `%v` (which
+	dumps their contents, or just an address for funcs and channels); all other
+	values are formatted with `%v`.
+*/
+func RegisterPanicStringer[T any](stringer func(T) string) {
+	panicStringers.Lock()
+	defer panicStringers.Unlock()
+	panicStringers.byType[reflect.TypeOf((*T)(nil)).Elem()] = func(v interface{}) string {
+		return stringer(v.(T))
+	}
+}
+
+// panicMessage describes a non-error panic value.
+func panicMessage(rec interface{}) string {
+	typ := reflect.TypeOf(rec)
+	panicStringers.RLock()
+	stringer := panicStringers.byType[typ]
+	panicStringers.RUnlock()
+	if stringer != nil {
+		return stringer(rec)
+	}
+	if typ == nil {
+		return fmt.Sprintf("%v", rec)
+	}
+	switch typ.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Sprintf("%s at %#x", typ, reflect.ValueOf(rec).Pointer())
+	}
+	return fmt.Sprintf("%v", rec)
+}
+
+// unknownPanic wraps a non-error panic value in an `UnknownPanicError`.
+func unknownPanic(rec interface{}) error {
+	return UnknownPanicError.NewWith(panicMessage(rec), errors.SetData(OriginalErrorKey, rec))
+}
@@ -0,0 +1,86 @@
+package try
+
+import (
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	A `Catch` handler built from prefab side effects, such as `Log`, `Metric`
+	and `Report`.  Handlers compose with `Then`, and `ThenRethrow` lets the
+	error keep propagating after the side effects, so the common pattern of
+	observing an error on its way through reads as a declaration:
+
+		try.Do(f).
+			Catch(errors.NetworkError, try.Log(nil).Then(try.Metric(c)).ThenRethrow()).
+			Done()
+*/
+type Handler func(err *errors.Error)
+
+/*
+	Counts errors for `Metric`, by `errors.MetricLabel`, so the number of
+	distinct labels is bounded by the error taxonomy.
+*/
+type Counter interface {
+	Inc(label string)
+}
+
+/*
+	Sends errors to somewhere like an error tracking service, for `Report`.
+*/
+type Reporter interface {
+	Report(err error)
+}
+
+/*
+	Returns a handler logging the error, with its stack, through `logf`, which
+	takes arguments like `log.Printf`.  A nil `logf` logs through
+	`errors.LogMethod`.
+*/
+func Log(logf func(format string, args ...interface{})) Handler {
+	return func(err *errors.Error) {
+		if logf == nil {
+			errors.LogMethod("%s", err)
+			return
+		}
+		logf("%s", err)
+	}
+}
+
+/*
+	Returns a handler counting the error with `counter`.
+*/
+func Metric(counter Counter) Handler {
+	return func(err *errors.Error) {
+		counter.Inc(errors.MetricLabel(err))
+	}
+}
+
+/*
+	Returns a handler sending the error to `reporter`.
+*/
+func Report(reporter Reporter) Handler {
+	return func(err *errors.Error) {
+		reporter.Report(err)
+	}
+}
+
+/*
+	Returns a handler running `h` and then `next`.
+*/
+func (h Handler) Then(next Handler) Handler {
+	return func(err *errors.Error) {
+		h(err)
+		next(err)
+	}
+}
+
+/*
+	Returns a handler running `h` and then rethrowing the error, so it
+	propagates on out of the plan as if the `Catch` weren't there.
+*/
+func (h Handler) ThenRethrow() Handler {
+	return func(err *errors.Error) {
+		h(err)
+		panic(err)
+	}
+}
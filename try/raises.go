@@ -0,0 +1,69 @@
+package try
+
+import (
+	"fmt"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	Marks the plan as a boundary of the function or package that declared
+	the classes it may raise with `errors.Raises(id, ...)`.  When
+	`errors.Config.Checkraises` is set, errors escaping the plan are checked
+	against the declaration, and `errors.OnUndeclaredRaise` hears about each
+	one it doesn't cover.  When `DiagnoseCatches` is set, catches for classes
+	unrelated to every declared class are reported as unreachable.
+
+	Without either setting, this costs nothing when the plan runs.
+*/
+func (p *Plan) Raises(id interface{}) *Plan {
+	p.raises = id
+	return p
+}
+
+// checkRaises checks a panic escaping the plan against its declaration.
+func (p *Plan) checkRaises(rec interface{}) {
+	if p.raises == nil || !errors.Config.Checkraises {
+		return
+	}
+	if err, ok := rec.(error); ok && errors.Undeclared(p.raises, err) {
+		errors.OnUndeclaredRaise(p.raises, err)
+	}
+}
+
+// diagnoseDeclared reports the typed catches whose classes neither descend
+// from, nor are ancestors of, any class declared for the plan.
+func (p *Plan) diagnoseDeclared() {
+	declared := errors.DeclaredRaises(p.raises)
+	if declared == nil {
+		return
+	}
+	var reports []string
+
+	diagnostics.mu.Lock()
+	for _, catch := range p.catch {
+		stats := statsFor(catch)
+		if stats == nil || stats.reported {
+			continue
+		}
+		related := false
+		for _, class := range declared {
+			if catch.match.Is(class) || class.Is(catch.match) {
+				related = true
+				break
+			}
+		}
+		if related {
+			continue
+		}
+		stats.reported = true
+		reports = append(reports, fmt.Sprintf(
+			"try: Catch(%s) %s can't match anything %v declares it raises",
+			catch.match.Path(), p.siteName(catch.site), p.raises))
+	}
+	diagnostics.mu.Unlock()
+
+	for _, report := range reports {
+		OnUnreachableCatch(report)
+	}
+}
@@ -0,0 +1,160 @@
+package try
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	// Panic type when several children of a `CollectAll` scope panic.  Use
+	// `ScopeFailures` to get at the individual errors.
+	ScopeError = errors.NewClass("Scope Error")
+
+	scopeFailuresKey = errors.GenSym()
+)
+
+// Decides how panics from a scope's children surface.
+type ScopePolicy int
+
+const (
+	// Cancel the scope's context at the first panic, wait for the other
+	// children to return, and re-raise just the first panic.
+	FailFast ScopePolicy = iota
+	// Let every child run to completion.  A single panic is re-raised as is;
+	// several are raised together as a `ScopeError`.
+	CollectAll
+)
+
+/*
+	A `Scope` is a nursery for goroutines: every goroutine started with `Go`
+	finishes before `RunScope` returns, and their panics are re-raised from
+	`RunScope`, in the caller's goroutine, where the enclosing plan's
+	handlers can catch them by class.
+*/
+type Scope struct {
+	policy   ScopePolicy
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	failures []error
+}
+
+/*
+	Runs `f` with a new `Scope`, waits for every goroutine it started with
+	`Go`, and then re-raises their panics according to `policy`.  A panic in
+	`f` itself is treated like a child's.  The scope's context, derived from
+	`ctx`, is canceled when the scope finishes (and, with `FailFast`, at the
+	first panic, with the panic as its `context.Cause`).
+*/
+func RunScope(ctx context.Context, policy ScopePolicy, f func(s *Scope)) {
+	s := &Scope{policy: policy}
+	ctx, s.cancel = context.WithCancelCause(ctx)
+	s.ctx = context.WithValue(ctx, scopeKey{}, s)
+	defer s.cancel(nil)
+	func() {
+		defer s.recover()
+		f(s)
+	}()
+	s.wg.Wait()
+	switch {
+	case len(s.failures) == 0:
+	case len(s.failures) == 1 || s.policy == FailFast:
+		Repanic(s.failures[0])
+	default:
+		msgs := make([]string, 0, len(s.failures))
+		for _, failure := range s.failures {
+			msgs = append(msgs, errors.GetMessage(failure))
+		}
+		panic(ScopeError.NewWith(
+			fmt.Sprintf("%d goroutines failed:\n%s", len(s.failures), strings.Join(msgs, "\n")),
+			errors.SetData(scopeFailuresKey, s.failures)))
+	}
+}
+
+// Starts `f` in a new goroutine belonging to the scope.
+func (s *Scope) Go(f func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.recover()
+		f()
+	}()
+}
+
+/*
+	Returns the scope's context.  Children should watch it to stop early when
+	a `FailFast` scope has already failed.
+*/
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Scope) recover() {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	if errors.IsFatalRuntimePanic(rec) && !ConsumeFatalRuntime {
+		panic(rec)
+	}
+	err, ok := rec.(error)
+	if !ok {
+		err = unknownPanic(rec)
+	}
+	errors.RecordPanicOrigin(err)
+	s.mu.Lock()
+	s.failures = append(s.failures, err)
+	s.mu.Unlock()
+	if s.policy == FailFast {
+		s.cancel(err)
+	}
+}
+
+type scopeKey struct{}
+
+/*
+	Cancels the scope's context, so its other children stop early, with
+	`cause` as the context's `context.Cause` (`context.Canceled` if nil).
+	This is for a child that catches an error, so no panic reaches the
+	scope, but whose failure still means the others should give up.
+*/
+func (s *Scope) Cancel(cause error) {
+	s.cancel(cause)
+}
+
+/*
+	Cancels the innermost scope `ctx` was derived from (see `Scope.Cancel`),
+	and reports whether there was one.  Handlers added with `CatchCtx` get
+	the plan's context, so in a child whose plan was made with `DoCtx` from
+	the scope's context, catching one worker's error can cleanly stop the
+	others:
+
+		s.Go(func() {
+			try.DoCtx(s.Context(), work).CatchCtx(FatalError, func(ctx context.Context, e *errors.Error) {
+				report(e)
+				try.Cancel(ctx, e)
+			}).Done()
+		})
+*/
+func Cancel(ctx context.Context, cause error) bool {
+	s, ok := ctx.Value(scopeKey{}).(*Scope)
+	if ok {
+		s.Cancel(cause)
+	}
+	return ok
+}
+
+/*
+	Returns the errors raised by the children of a `CollectAll` scope, given
+	the `ScopeError` it raised, in the order they failed.  Values that
+	weren't errors are wrapped in `UnknownPanicError`s.
+*/
+func ScopeFailures(err error) []error {
+	failures, _ := errors.GetData(err, scopeFailuresKey).([]error)
+	return failures
+}
@@ -0,0 +1,54 @@
+package try
+
+import (
+	"sync"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+/*
+	A `Token` holds a suspended plan's handlers and `Finally` blocks until the
+	outcome of the plan's asynchronous work is known.  See `Plan.Suspend`.
+*/
+type Token struct {
+	plan *Plan
+	once sync.Once
+}
+
+/*
+	Suspends the plan instead of running it, for asynchronous or event-driven
+	code that can't fit the single-scope `Done` model.  The plan's main
+	function is ignored; instead, the returned `Token` is resumed later (for
+	example, from a callback) with the outcome of the work.
+*/
+func (p *Plan) Suspend() *Token {
+	return &Token{plan: p}
+}
+
+/*
+	Resumes a suspended plan with the outcome of its work.  A nil `err` runs
+	the success path (just the `Finally` blocks); otherwise `err` is handled
+	exactly as if the plan's main function had panicked with it, and if no
+	handler consumes it, `Resume` panics with it after the `Finally` blocks
+	run.
+
+	A token may only be resumed once; later calls panic with a
+	`ProgrammerError`.
+*/
+func (t *Token) Resume(err error) {
+	resumed := false
+	t.once.Do(func() {
+		resumed = true
+		p := *t.plan
+		p.main = func() {
+			if err != nil {
+				panic(err)
+			}
+		}
+		t.plan.markRan()
+		p.Done()
+	})
+	if !resumed {
+		panic(errors.ProgrammerError.New("try.Token resumed twice"))
+	}
+}
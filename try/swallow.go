@@ -0,0 +1,66 @@
+package try
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	/*
+		Set `MonitorSwallowed` to have plans watch for `CatchAll` handlers that
+		consume an error without doing anything observable about it: the
+		handler neither rethrew nor logged through the errors package (see
+		`errors.LogCount`).  Such errors are counted (see `SwallowedCount`) and
+		one in every `SwallowedSampleRate` of them is logged with the handler's
+		location.
+
+		Logging from other goroutines while a handler runs can hide a
+		swallowed error, so treat the results as a sample.
+	*/
+	MonitorSwallowed = false
+
+	// One in this many swallowed errors is logged when monitoring.
+	SwallowedSampleRate uint64 = 100
+
+	swallowed uint64
+)
+
+// Returns how many errors have been swallowed by `CatchAll` handlers while
+// `MonitorSwallowed` was set.
+func SwallowedCount() uint64 {
+	return atomic.LoadUint64(&swallowed)
+}
+
+// runCatchAll runs a `CatchAll` handler, watching it for swallowing if
+// monitoring is on.
+func runCatchAll(handler func(error), err error) {
+	if !MonitorSwallowed {
+		handler(err)
+		return
+	}
+	before := errors.LogCount()
+	handler(err)
+	if errors.LogCount() != before {
+		return
+	}
+	n := atomic.AddUint64(&swallowed, 1)
+	if SwallowedSampleRate > 0 && (n-1)%SwallowedSampleRate == 0 {
+		errors.LogMethod("try: error swallowed by CatchAll handler %s: %s",
+			funcLocation(handler), errors.GetMessage(err))
+	}
+}
+
+// funcLocation describes where a function was declared.
+func funcLocation(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	file, line := fn.FileLine(fn.Entry())
+	return fmt.Sprintf("%s:%s:%d", fn.Name(), filepath.Base(file), line)
+}
@@ -11,23 +11,46 @@
 	  - `Finally(func() {...your handler...})`
 
 	`Catch` and `CatchAll` blocks consume the error -- it will not be re-raised
-	unless the handlers explicitly do so.  `Finally` blocks run even in the
-	absense of errors (much like regular defers), and do not consume errors --
-	they will be re-raised after the execution of the `Finally` block.
+	unless the handlers explicitly do so.  (`CatchErr` and `CatchAllErr` take
+	handlers that return an error; returning non-nil re-raises it.)
+	`Finally` blocks run even in the absense of errors (much like regular
+	defers), and do not consume errors -- they will be re-raised after the
+	execution of the `Finally` block.
 
 	Matching of errors occurs in order.  This has a few implications:
 	  - If using `Catch` blocks with errors that are subclasses of other errors
 	    you're handling in the same sequence, put the most specific ones first.
+	  - `Catch` only looks at the outermost layer of an error that wraps other
+	    spacemonkey errors; see `CatchLayer` and `CatchEach` to match on (and
+	    get at) the inner layers.
 	  - `CatchAll` blocks should be last (or they'll eat all errors,
 	    even if you declare more `Catch` blocks later).
 
+	Errors from `errors.Pooled` classes are released back to their pool once
+	a `Catch` or `CatchAll` handler returns without rethrowing, so handlers
+	must not keep references to them.
+
+	Errors from classes declared `errors.Fatal` are never consumed either;
+	after the `Finally` blocks run they are passed to `errors.ReportFatal`
+	and keep propagating.
+
+	Panics from the runtime in states that are unsafe to recover from (see
+	`errors.IsFatalRuntimePanic`) are never consumed, unless
+	`ConsumeFatalRuntime` is set.
+
+	Spacemonkey errors passing through a plan are stamped with how long the
+	plan had been running (see `errors.GetDuration`); an inner plan's timing
+	takes precedence over outer ones.  See `TimePlans` to turn this off.
+
 	`Finally` blocks will be run at the end of the error handling sequence
 	regardless of their declaration order.
 
 	Additional panics from a `Catch` or `CatchAll` block will still cause
 	`Finally` blocks to be executed.  However, note that additional panics
 	raised from any handler blocks will cause the original error to be masked
-	-- be careful of this.
+	-- be careful of this.  Such panics are raised as a `HandlerError` which
+	refers back to the original error, unless they're a rethrow; see
+	`HandlerError`.
 
 	Panics with values that are not spacemonkey errors will be handled
 	(no special treatment; they'll hit `CatchAll` blocks and `Finally` blocks;
@@ -40,7 +63,8 @@
 
 	A `try.Do(func() {...})` with no attached errors handlers is legal but
 	pointless.  A `try.Do(func() {...})` with no `Done()` will never run the
-	function (which is good; you won't forget to call it).
+	function (which is good; you won't forget to call it).  Plans run once;
+	see `Reusable` to run one repeatedly.
 
 	For spacemonkey errors, the 'exit' path will be automatically recorded for
 	each time the errors is rethrown.  This is not a complete record of where
@@ -49,6 +73,10 @@
 	(unless of course they raise a new error!), since they are functions that
 	return normally.
 
+	`Done()` is cheap when nothing panics: the success path costs a single
+	defer, a clock read (see `TimePlans`) and no allocations, so it's fine
+	to wrap hot paths in plans.  See the benchmarks in this package.
+
 	A note about use cases: while `try` should be familiar and comfortable
 	to users of exceptions in other languages, and we feel use of a "typed"
 	panic mechanism results in effective error handling with a minimization
@@ -56,21 +84,43 @@
 	always the correct answer!  There are at least two situations where you
 	may want to consider converting back to handling errors as regular values:
 	If passing errors between goroutines, of course you need to pass them
-	as values.  Another situation where error returns are more capable than
-	panics is when part of multiple-returns, where the other values may have
-	been partially assembled and still may need be subject to cleanup by the
-	caller.  Fortunately, you can always use `CatchAll` to easily fence a block
+	as values (`RunScope` does this for you, re-raising panics from the
+	goroutines it starts in the goroutine that started them).  Another
+	situation where error returns are more capable than panics is when part
+	of multiple-returns, where the other values may have been partially
+	assembled and still may need be subject to cleanup by the caller.  Fortunately, you can always use `CatchAll` to easily fence a block
 	of panic-oriented code and convert it into errors-by-value flow.
 */
 package try
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"sort"
+	"time"
 
 	"github.com/spacemonkeygo/errors"
 )
 
 var (
+	// Set to let handlers consume panics that `errors.IsFatalRuntimePanic`
+	// reports as unsafe to recover from.  By default plans never consume them
+	// (though `Finally` blocks still run), since swallowing an unrecoverable
+	// runtime state leads to corrupted processes.  When set, such panics are
+	// handled as `errors.FatalRuntimeError`s.
+	ConsumeFatalRuntime = false
+
+	// Set to record, on every error a plan consumes, a description of the
+	// handler that consumed it, for `errors.GetHandledBy` and `errors.OnCaught`
+	// observers.  Describing handlers is slow, so this is meant for debugging
+	// questions like "why didn't my Catch fire".
+	AuditHandlers = false
+
+	// Set to false to stop plans from timing themselves for
+	// `errors.GetDuration`, saving a clock read in every `Done()`.
+	TimePlans = true
+
 	// Panic type when a panic is caught that is neither a spacemonkey error, nor an ordinary golang error.
 	// For example, panic("hooray!")
 	UnknownPanicError = errors.NewClass("Unknown Error")
@@ -80,39 +130,211 @@ var (
 )
 
 type Plan struct {
-	main    func()
-	catch   []check
-	finally func()
+	name        string
+	main        func()
+	catch       []check
+	finally     func()
+	transparent []*errors.ErrorClass
+	reusable    bool
+	watch       *planWatch
+	after       []func(Outcome)
+	// the check that ran and the error it was given, for `After`.
+	matched *check
+	handled error
+	// the consumed error, for `After` to release once it has run.
+	release *errors.Error
+	ran         bool
+	captureLogs int
+	capture     *logRing
+	started     time.Time
+	ctx         context.Context
+	// the id given to `Raises`, if any.
+	raises interface{}
 }
 
 type check struct {
 	match      *errors.ErrorClass
+	layer      Layer
 	handler    func(err *errors.Error)
 	anyhandler func(err error)
+	// all is set for `CatchAll`s, which have no class.
+	all bool
+	// site is where the catch was added, under `DiagnoseCatches`.
+	site uintptr
+	// orig is the handler as the user gave it, if handler or anyhandler
+	// wraps it.
+	orig interface{}
 }
 
+/*
+	Chooses which layer of an error a `CatchLayer` handler is given, when the
+	error's chain of wrapped spacemonkey errors has several classed layers.
+*/
+type Layer int
+
+const (
+	// Match only on the outermost layer's class, and hand it to the handler.
+	// This is what `Catch` does.
+	Outermost Layer = iota
+	// Match if any layer belongs to the class, and hand the handler the
+	// innermost such layer; usually the one that carries the data keys set
+	// where the error was first raised.
+	Innermost
+)
+
 func Do(f func()) *Plan {
-	return &Plan{main: f, finally: func() {}}
+	p := &Plan{main: f}
+	if WatchPlans {
+		watchPlan(p)
+	}
+	return p
+}
+
+/*
+	Like `Do`, but gives the plan a human-readable operation name.
+
+	Exits recorded on spacemonkey errors passing through a named plan are
+	labeled with the name, so reports read as "error escaping plan 'load-config'"
+	rather than pointing at an anonymous closure.
+*/
+func DoNamed(name string, f func()) *Plan {
+	p := Do(f)
+	p.name = name
+	return p
+}
+
+// Returns the name given to `DoNamed`, or the empty string for plans made by `Do`.
+func (p *Plan) Name() string {
+	return p.name
 }
 
 func (p *Plan) Catch(kind *errors.ErrorClass, handler func(err *errors.Error)) *Plan {
 	p.catch = append(p.catch, check{
 		match:   kind,
 		handler: handler,
+		site:    callerPC(),
+	})
+	return p
+}
+
+/*
+	Like `Catch`, but with a choice of layer semantics when the error wraps
+	other spacemonkey errors.  `Catch` is `CatchLayer` with `Outermost`.
+*/
+func (p *Plan) CatchLayer(kind *errors.ErrorClass, layer Layer, handler func(err *errors.Error)) *Plan {
+	p.catch = append(p.catch, check{
+		match:   kind,
+		layer:   layer,
+		handler: handler,
+		site:    callerPC(),
 	})
 	return p
 }
 
+/*
+	Catches errors with any layer belonging to `kind`, and gives the handler
+	an iterator over every such layer, outermost first.  See
+	`errors.ErrorClass.Layers`.
+*/
+func (p *Plan) CatchEach(kind *errors.ErrorClass, handler func(layers iter.Seq[*errors.Error])) *Plan {
+	p.CatchLayer(kind, allLayers, func(err *errors.Error) {
+		handler(kind.Layers(err))
+	})
+	p.catch[len(p.catch)-1].orig = handler
+	return p
+}
+
 func (p *Plan) CatchAll(handler func(err error)) *Plan {
 	p.catch = append(p.catch, check{
-		match:      nil,
 		anyhandler: handler,
+		all:        true,
+	})
+	return p
+}
+
+/*
+	Like `Catch`, but the handler returns an error instead of panicking to
+	rethrow.  A non-nil return is raised from the handler just as if it had
+	called `panic` with it; a nil return consumes the error.
+*/
+func (p *Plan) CatchErr(kind *errors.ErrorClass, handler func(err *errors.Error) error) *Plan {
+	p.Catch(kind, func(err *errors.Error) {
+		if rethrow := handler(err); rethrow != nil {
+			panic(deliberate{rethrow})
+		}
 	})
+	p.catch[len(p.catch)-1].orig = handler
+	return p
+}
+
+/*
+	Adds a `Catch` for each class in the table.  The catches are ordered
+	most-specific-first, deepest classes in the hierarchy before their
+	ancestors (and by class path among classes at the same depth), so a
+	large set of handlers can be declared as data, and reused across plans,
+	without the ordering mistakes easy to make in a long fluent chain.
+	Catches added before or after the table keep their place.
+*/
+func (p *Plan) CatchTable(table map[*errors.ErrorClass]func(err *errors.Error)) *Plan {
+	classes := make([]*errors.ErrorClass, 0, len(table))
+	for class := range table {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		di, dj := depth(classes[i]), depth(classes[j])
+		if di != dj {
+			return di > dj
+		}
+		return classes[i].Path() < classes[j].Path()
+	})
+	for _, class := range classes {
+		p.Catch(class, table[class])
+	}
+	return p
+}
+
+func depth(class *errors.ErrorClass) (n int) {
+	for ; class != nil; class = class.Parent() {
+		n++
+	}
+	return n
+}
+
+/*
+	Like `CatchAll`, but the handler returns an error instead of panicking to
+	rethrow.  A non-nil return is raised from the handler just as if it had
+	called `panic` with it; a nil return consumes the error.
+
+	Returning a wrapped non-error panic is equivalent to calling `Repanic`.
+*/
+func (p *Plan) CatchAllErr(handler func(err error) error) *Plan {
+	p.CatchAll(func(err error) {
+		if rethrow := handler(err); rethrow != nil {
+			panic(deliberate{OriginalError(rethrow)})
+		}
+	})
+	p.catch[len(p.catch)-1].orig = handler
+	return p
+}
+
+/*
+	Marks error classes the plan must never catch, even with `CatchAll`.
+	Errors in these families propagate out of the plan untouched (after its
+	`Finally` blocks run), as though it had no handlers.  This is useful for
+	framework-level classes, like a shutdown request, that application-level
+	handlers must not swallow.
+*/
+func (p *Plan) Transparent(classes ...*errors.ErrorClass) *Plan {
+	p.transparent = append(p.transparent, classes...)
 	return p
 }
 
 func (p *Plan) Finally(f func()) *Plan {
 	f2 := p.finally
+	if f2 == nil {
+		p.finally = f
+		return p
+	}
 	p.finally = func() {
 		f()
 		f2()
@@ -120,69 +342,257 @@ func (p *Plan) Finally(f func()) *Plan {
 	return p
 }
 
+/*
+	Marks the plan as safe to run more than once.  Plans are single-shot by
+	default: calling `Done` a second time panics with an `errors.ProgrammerError`
+	rather than quietly running the main function again.
+*/
+func (p *Plan) Reusable() *Plan {
+	p.reusable = true
+	return p
+}
+
+// Returns true once `Done` has started running the plan's main function.
+func (p *Plan) Ran() bool {
+	return p.ran
+}
+
 func (p *Plan) Done() {
+	if p.ran && !p.reusable {
+		panic(errors.ProgrammerError.New("try: Done called twice on the same plan"))
+	}
+	p.markRan()
+	if p.captureLogs > 0 {
+		p.startCapture()
+	}
+	if TimePlans {
+		p.started = time.Now()
+	}
+	tracked := TrackNesting
+	if tracked {
+		pushPlan(p)
+	}
+	if p.after != nil {
+		defer p.runAfter()
+	}
+	if DiagnoseCatches && p.raises != nil {
+		p.diagnoseDeclared()
+	}
+	defer p.handle(tracked)
+	if err := p.injectedFault(); err != nil {
+		panic(err)
+	}
+	p.main()
+}
+
+// handle is deferred by Done.  On the success path it only runs the finally
+// blocks, so a plan that doesn't panic costs a single defer.
+func (p *Plan) handle(tracked bool) {
+	if tracked {
+		popPlan()
+	}
+	if p.capture != nil {
+		p.stopCapture()
+	}
+	rec := recover()
+	if rec == nil {
+		p.runFinally(nil)
+		return
+	}
+	if TimePlans {
+		errors.RecordDuration(asError(rec), time.Since(p.started))
+	}
+	if p.capture != nil {
+		p.attachCapture(rec)
+	}
+	consumed := false
+	var fatal error
+	// the check whose handler is running, and the error it was given.
+	var active *check
+	var handled error
+	// the consumed error, returned to its pool once nothing can see it.
+	var release *errors.Error
 	defer func() {
-		rec := recover()
-		consumed := false
-		defer func() {
-			p.finally()
-			if !consumed {
-				panic(rec)
+		if p.after != nil {
+			p.matched, p.handled = active, handled
+		}
+		if active != nil {
+			if r := recover(); r != nil {
+				r = handlerPanic(active.describe, r, rec, handled)
+				p.runFinally(rec)
+				panic(r)
 			}
-		}()
-		switch err := rec.(type) {
-		case nil:
-			consumed = true
-			return
-		case *errors.Error:
-			// record the origin location of the error.
-			// this is redundant at first, but useful if the error is rethrown;
-			// then it shows line of the panic that rethrew it.
-			errors.RecordBefore(err, 3)
-			// run all checks
-			for _, catch := range p.catch {
-				if catch.match == nil {
-					consumed = true
-					catch.anyhandler(err)
-					return
-				}
-				if err.Is(catch.match) {
-					consumed = true
-					catch.handler(err)
-					return
-				}
+		}
+		p.runFinally(rec)
+		if release != nil {
+			if p.after != nil {
+				p.release = release
+			} else {
+				release.Release()
 			}
-		case error:
-			// grabbag error, so skip all the typed catches, but still do wildcards and finally.
-			for _, catch := range p.catch {
-				if catch.match == nil {
-					consumed = true
-					catch.anyhandler(err)
-					return
-				}
+		}
+		if !consumed {
+			p.checkRaises(rec)
+			if fatal != nil {
+				errors.ReportFatal(fatal)
+			}
+			panic(rec)
+		}
+	}()
+	handling := rec
+	if errors.IsFatalRuntimePanic(rec) {
+		if !ConsumeFatalRuntime {
+			return
+		}
+		handling = errors.FatalRuntimeError.Wrap(rec.(error))
+	}
+	if p.isTransparent(handling) {
+		return
+	}
+	if err, ok := handling.(error); ok && errors.IsFatal(err) {
+		fatal = err
+		return
+	}
+	switch err := handling.(type) {
+	case *errors.Error:
+		errors.RecordPanicOrigin(err)
+		if DiagnoseCatches {
+			p.diagnose(err)
+		}
+		// find the first matching check, if any.
+		var match *check
+		for i, catch := range p.catch {
+			if catch.matches(err) {
+				match = &p.catch[i]
+				break
 			}
+		}
+		// record the origin location of the error.
+		// this is redundant at first, but useful if the error is rethrown;
+		// then it shows line of the panic that rethrew it.
+		errors.RecordBeforeLabeled(err, 3, p.exitLabel(match != nil))
+		// run the matching check
+		if match == nil {
+			return
+		}
+		consumed = true
+		active, handled = match, err
+		match.caught(p.ctx, err)
+		switch {
+		case match.catchAll():
+			runCatchAll(match.anyhandler, err)
+		case match.layer == Innermost:
+			match.handler(match.match.Innermost(err))
 		default:
-			// handle the case where it's not even an error type.
-			// we'll wrap your panic in an UnknownPanicError and add the original as data for later retrieval.
-			for _, catch := range p.catch {
-				if catch.match == nil {
-					consumed = true
-					msg := fmt.Sprintf("%v", rec)
-					pan := UnknownPanicError.NewWith(msg, errors.SetData(OriginalErrorKey, rec))
-					catch.anyhandler(pan)
-					return
+			match.handler(err)
+		}
+		// the handler consumed the error without rethrowing it.
+		release = err
+	case error:
+		// grabbag error, so skip all the typed catches, but still do wildcards and finally.
+		for _, catch := range p.catch {
+			if catch.catchAll() {
+				consumed = true
+				active, handled = &catch, err
+				catch.caught(p.ctx, err)
+				runCatchAll(catch.anyhandler, err)
+				return
+			}
+		}
+	default:
+		// handle the case where it's not even an error type.
+		// we'll wrap your panic in an UnknownPanicError and add the original as data for later retrieval.
+		for _, catch := range p.catch {
+			if catch.catchAll() {
+				consumed = true
+				pan := unknownPanic(rec)
+				errors.RecordPanicOrigin(pan)
+				if TimePlans {
+					errors.RecordDuration(pan, time.Since(p.started))
 				}
-				if UnknownPanicError.Is(catch.match) {
-					consumed = true
-					msg := fmt.Sprintf("%v", rec)
-					pan := UnknownPanicError.NewWith(msg, errors.SetData(OriginalErrorKey, rec))
-					catch.handler(pan.(*errors.Error))
-					return
+				active, handled = &catch, pan
+				catch.caught(p.ctx, pan)
+				runCatchAll(catch.anyhandler, pan)
+				return
+			}
+			if UnknownPanicError.Is(catch.match) {
+				consumed = true
+				pan := unknownPanic(rec)
+				errors.RecordPanicOrigin(pan)
+				if TimePlans {
+					errors.RecordDuration(pan, time.Since(p.started))
 				}
+				active, handled = &catch, pan
+				catch.caught(p.ctx, pan)
+				catch.handler(pan.(*errors.Error))
+				return
 			}
 		}
-	}()
-	p.main()
+	}
+}
+
+// allLayers is the Layer used by CatchEach, whose handler walks the layers
+// itself.
+const allLayers Layer = -1
+
+// catchAll reports whether the check is a `CatchAll`.
+func (c *check) catchAll() bool {
+	return c.all
+}
+
+// matches reports whether the check handles err.  A typed catch for a nil
+// class matches nothing.
+func (c *check) matches(err *errors.Error) bool {
+	switch {
+	case c.catchAll():
+		return true
+	case c.match == nil:
+		return false
+	case c.layer == Outermost:
+		return err.Is(c.match)
+	default:
+		return c.match.Innermost(err) != nil
+	}
+}
+
+// asError returns rec if it's an error, or nil.
+func asError(rec interface{}) error {
+	err, _ := rec.(error)
+	return err
+}
+
+func (p *Plan) isTransparent(rec interface{}) bool {
+	err, ok := rec.(error)
+	if !ok {
+		return false
+	}
+	for _, class := range p.transparent {
+		if class.Contains(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// runFinally runs the finally blocks, given the in-flight panic value, if
+// any.
+func (p *Plan) runFinally(rec interface{}) {
+	if p.finally == nil {
+		return
+	}
+	defer p.recoverFinally(rec)
+	p.finally()
+}
+
+// exitLabel describes an error passing through the plan, for exit records.
+func (p *Plan) exitLabel(caught bool) string {
+	if p.name == "" {
+		return ""
+	}
+	if caught {
+		return fmt.Sprintf("caught in plan '%s'", p.name)
+	}
+	return fmt.Sprintf("error escaping plan '%s'", p.name)
 }
 
 /*
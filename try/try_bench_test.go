@@ -0,0 +1,34 @@
+package try_test
+
+import (
+	"testing"
+
+	"github.com/spacemonkeygo/errors"
+	"github.com/spacemonkeygo/errors/try"
+)
+
+func BenchmarkDoSuccess(b *testing.B) {
+	b.ReportAllocs()
+	// plans are single-shot unless marked Reusable.
+	plan := try.Do(func() {}).Finally(func() {}).Catch(FruitError, func(e *errors.Error) {}).Reusable()
+	for i := 0; i < b.N; i++ {
+		plan.Done()
+	}
+}
+
+func BenchmarkDoSuccessNewPlan(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		try.Do(func() {}).CatchAll(func(e error) {}).Done()
+	}
+}
+
+func BenchmarkDoCatch(b *testing.B) {
+	b.ReportAllocs()
+	err := AppleError.New("emsg")
+	for i := 0; i < b.N; i++ {
+		try.Do(func() {
+			panic(err)
+		}).Catch(FruitError, func(e *errors.Error) {}).Done()
+	}
+}
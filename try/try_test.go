@@ -1,7 +1,10 @@
 package try_test
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"strings"
 
 	"github.com/spacemonkeygo/errors"
 	"github.com/spacemonkeygo/errors/try"
@@ -49,14 +52,14 @@ func ExampleCrashInCatch() {
 			panic(fmt.Errorf("zomg"))
 		}).Done()
 	}).CatchAll(func(e error) {
-		fmt.Println("outer error caught:", e.Error())
+		fmt.Println("outer error caught:", errors.GetMessage(e))
 	}).Done()
 
 	// Output:
 	// function called
 	// catch wildcard called
 	// finally block called
-	// outer error caught: zomg
+	// outer error caught: Handler Error: zomg
 }
 
 func ExampleErrorsLeaveFinally() {
@@ -88,13 +91,13 @@ func ExampleCrashInFinally() {
 			fmt.Println("catch wildcard called")
 		}).Done()
 	}).CatchAll(func(e error) {
-		fmt.Println("outer error caught:", e.Error())
+		fmt.Println("outer error caught:", errors.GetMessage(e))
 	}).Done()
 
 	// Output:
 	// function called
 	// finally block called
-	// outer error caught: zomg
+	// outer error caught: Handler Error: zomg
 }
 
 var FruitError = errors.NewClass("fruit")
@@ -326,3 +329,189 @@ func ExampleObjectRepanicOriginal() {
 	// finally block called
 	// outer error equals original: true
 }
+
+func ExampleNamedPlan() {
+	try.Do(func() {
+		try.DoNamed("load-config", func() {
+			panic(AppleError.New("emsg"))
+		}).Catch(RockError, func(e *errors.Error) {
+			fmt.Println("rock handler called")
+		}).Done()
+	}).CatchAll(func(e error) {
+		exits := errors.GetExits(e)
+		fmt.Println(strings.Contains(exits, "(error escaping plan 'load-config')"))
+	}).Done()
+
+	// Output:
+	// true
+}
+
+func ExampleReturningHandlers() {
+	try.Do(func() {
+		try.Do(func() {
+			panic(AppleError.New("emsg"))
+		}).CatchErr(RockError, func(e *errors.Error) error {
+			fmt.Println("rock handler called")
+			return nil
+		}).CatchAllErr(func(e error) error {
+			fmt.Println("catch wildcard called")
+			return e
+		}).Done()
+	}).CatchAllErr(func(e error) error {
+		fmt.Println("outer error caught:", AppleError.Contains(e))
+		return nil
+	}).Done()
+
+	// Output:
+	// catch wildcard called
+	// outer error caught: true
+}
+
+func ExampleDeferToParent() {
+	try.TrackNesting = true
+	defer func() { try.TrackNesting = false }()
+
+	helper := func() {
+		try.Do(func() {
+			fmt.Println("helper acquires resource")
+			try.DeferToParent(func() {
+				fmt.Println("resource released")
+			})
+		}).Finally(func() {
+			fmt.Println("helper finally block called")
+		}).Done()
+	}
+
+	try.Do(func() {
+		helper()
+		fmt.Println("outer operation continues")
+	}).Finally(func() {
+		fmt.Println("outer finally block called")
+	}).Done()
+
+	// Output:
+	// helper acquires resource
+	// helper finally block called
+	// outer operation continues
+	// resource released
+	// outer finally block called
+}
+
+func ExampleSuspend() {
+	callback := func(token *try.Token) {
+		// some time later, the asynchronous work fails
+		token.Resume(AppleError.New("emsg"))
+	}
+
+	token := try.Do(nil).Catch(FruitError, func(e *errors.Error) {
+		fmt.Println("fruit handler called")
+	}).Finally(func() {
+		fmt.Println("finally block called")
+	}).Suspend()
+	callback(token)
+
+	// Output:
+	// fruit handler called
+	// finally block called
+}
+
+var ShutdownRequested = errors.NewClass("shutdown requested")
+
+func ExampleTransparent() {
+	try.Do(func() {
+		try.Do(func() {
+			panic(ShutdownRequested.New("bye"))
+		}).Transparent(ShutdownRequested).CatchAll(func(e error) {
+			fmt.Println("application handler called")
+		}).Finally(func() {
+			fmt.Println("finally block called")
+		}).Done()
+	}).Catch(ShutdownRequested, func(e *errors.Error) {
+		fmt.Println("framework handler called")
+	}).Done()
+
+	// Output:
+	// finally block called
+	// framework handler called
+}
+
+var RequestError = errors.NewClass("request error")
+var RetryError = errors.NewClass("retry error")
+var RequestIDKey = errors.GenSym()
+
+func ExampleCatchLayer() {
+	raise := func() {
+		inner := RequestError.NewWith("upstream failed", errors.SetData(RequestIDKey, "req-1"))
+		panic(RequestError.Wrap(RetryError.Wrap(inner), errors.SetData(RequestIDKey, "req-2")))
+	}
+
+	try.Do(raise).CatchLayer(RequestError, try.Innermost, func(e *errors.Error) {
+		fmt.Println("innermost:", e.GetData(RequestIDKey))
+	}).Done()
+
+	try.Do(raise).CatchEach(RequestError, func(layers iter.Seq[*errors.Error]) {
+		for layer := range layers {
+			fmt.Println("layer:", layer.GetData(RequestIDKey))
+		}
+	}).Done()
+
+	// Output:
+	// innermost: req-1
+	// layer: req-2
+	// layer: req-1
+}
+
+func ExampleDoCtx() {
+	ctx, cancel := context.WithCancel(context.Background())
+	try.DoCtx(ctx, func(ctx context.Context) {
+		cancel()
+		panic(AppleError.New("canceled mid-operation"))
+	}).CatchCtx(FruitError, func(ctx context.Context, e *errors.Error) {
+		fmt.Println("handler sees:", ctx.Err())
+	}).FinallyCtx(func(ctx context.Context) {
+		fmt.Println("cleanup sees:", ctx.Err())
+	}).Done()
+
+	// Output:
+	// handler sees: context canceled
+	// cleanup sees: context canceled
+}
+
+type printCounter struct{}
+
+func (printCounter) Inc(label string) { fmt.Println("counted:", label) }
+
+func ExampleHandler() {
+	logf := func(format string, args ...interface{}) {
+		fmt.Println("logged:", errors.GetMessage(args[0].(error)))
+	}
+
+	try.Do(func() {
+		try.Do(func() {
+			panic(AppleError.New("bruised"))
+		}).Catch(FruitError, try.Log(logf).Then(try.Metric(printCounter{})).ThenRethrow()).Done()
+	}).Catch(FruitError, func(e *errors.Error) {
+		fmt.Println("outer caught:", e.Message())
+	}).Done()
+
+	// Output:
+	// logged: apple: bruised
+	// counted: Error/fruit
+	// outer caught: apple: bruised
+}
+
+func ExampleGo() {
+	try.Do(func() {
+		wait := try.Go(func() {
+			panic(AppleError.New("rotten in the background"))
+		})
+		fmt.Println("main function continues")
+		wait()
+	}).Catch(FruitError, func(e *errors.Error) {
+		fmt.Println("caught:", e.Message())
+	}).Done()
+
+	// Output:
+	// main function continues
+	// caught: apple: rotten in the background
+}
@@ -0,0 +1,75 @@
+package try
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spacemonkeygo/errors"
+)
+
+var (
+	/*
+		Set to track every plan made by `Do`, `DoNamed` and `DoCtx`, and call
+		`OnForgottenPlan` for each one that's garbage collected without `Done`
+		having been called.  A forgotten `Done()` silently skips the whole
+		block, and nothing else notices.  Watching costs a stack capture and
+		a runtime cleanup per plan, which is fine for a test run but adds up
+		on hot paths.
+	*/
+	WatchPlans = false
+
+	/*
+		Called with the creation stack of each forgotten plan when
+		`WatchPlans` is set.  It runs on the runtime's cleanup goroutine,
+		whenever the collector gets to the plan, so it must not block and
+		must synchronize whatever it records.  The default logs the stack.
+		To fail a test run instead, record the stacks, and check them in
+		`TestMain` after a `runtime.GC()`.
+	*/
+	OnForgottenPlan = func(creation string) {
+		errors.LogMethod("try: plan garbage collected without Done(), created at:\n%s", creation)
+	}
+)
+
+// planWatch is what a watched plan's cleanup sees; it mustn't reference the
+// plan, or the plan would never be collected.
+type planWatch struct {
+	ran   uint32
+	stack []uintptr
+}
+
+// watchPlan arranges for `OnForgottenPlan` to hear about p if it's collected
+// before it runs.
+func watchPlan(p *Plan) {
+	var pcs [64]uintptr
+	w := &planWatch{stack: append([]uintptr(nil), pcs[:runtime.Callers(3, pcs[:])]...)}
+	p.watch = w
+	runtime.AddCleanup(p, func(w *planWatch) {
+		if atomic.LoadUint32(&w.ran) == 0 {
+			OnForgottenPlan(w.creation())
+		}
+	}, w)
+}
+
+func (w *planWatch) creation() string {
+	var lines []string
+	frames := runtime.CallersFrames(w.stack)
+	for {
+		frame, more := frames.Next()
+		lines = append(lines, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			return strings.Join(lines, "\n")
+		}
+	}
+}
+
+// markRan records that the plan ran, for `Done`'s reuse check and the
+// watchdog.
+func (p *Plan) markRan() {
+	p.ran = true
+	if p.watch != nil {
+		atomic.StoreUint32(&p.watch.ran, 1)
+	}
+}
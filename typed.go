@@ -0,0 +1,163 @@
+// Copyright (C) 2014 Space Monkey, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TypedClass is an error class whose errors carry a struct of typed fields,
+// bridging class hierarchy matching with Go's struct-error idiom. The fields
+// are stored as data on the error and rendered into its message with a
+// text/template. For example:
+//
+//	type QuotaExceeded struct{ Limit, Used int }
+//
+//	var QuotaError = errors.NewTypedClass[QuotaExceeded](LimitError,
+//		"Quota Error", "used {{.Used}} of {{.Limit}}")
+//
+//	err := QuotaError.New(QuotaExceeded{Limit: 10, Used: 12})
+//	if fields, ok := QuotaError.Fields(err); ok { ... }
+//
+// A TypedClass embeds its *ErrorClass, so Contains, NewClass and the rest
+// work as usual.
+type TypedClass[T any] struct {
+	*ErrorClass
+	tmpl *template.Template
+	// format renders messages instead of tmpl, if set.
+	format func(T) string
+	key    DataKey
+}
+
+// NewTypedClass creates a TypedClass descending from parent. The template is
+// executed with the fields to render each error's message; it panics if the
+// template doesn't parse. The template is also set as the class'
+// MessageTemplate.
+func NewTypedClass[T any](parent *ErrorClass, name, tmpl string,
+	options ...ErrorOption) *TypedClass[T] {
+	options = append([]ErrorOption{MessageTemplate(tmpl), MessageFormat("")},
+		options...)
+	return &TypedClass[T]{
+		ErrorClass: parent.NewClass(name, options...),
+		tmpl:       template.Must(template.New(name).Parse(tmpl)),
+		key:        GenSym()}
+}
+
+// render executes the class' template with fields.
+func (c *TypedClass[T]) render(fields T) string {
+	if c.format != nil {
+		return c.format(fields)
+	}
+	var buf strings.Builder
+	if err := c.tmpl.Execute(&buf, fields); err != nil {
+		return fmt.Sprintf("%+v", fields)
+	}
+	return buf.String()
+}
+
+// New makes an error of the class with the given fields and error-specific
+// options.
+func (c *TypedClass[T]) New(fields T, options ...ErrorOption) error {
+	return c.newFrom(1, fields, options)
+}
+
+// newFrom is New for entry points built on it, callers frames up from the
+// caller's code, as for wrapFrom.
+func (c *TypedClass[T]) newFrom(callers int, fields T,
+	options []ErrorOption) error {
+	options = append(options, SetData(c.key, fields))
+	return c.wrapFrom(callers+1, fmt.Errorf("%s", c.render(fields)), nil,
+		options)
+}
+
+// Fields returns the fields of err, if it (or an error it wraps) was made by
+// the class.
+func (c *TypedClass[T]) Fields(err error) (fields T, ok bool) {
+	walk(err, func(candidate error) bool {
+		fields, ok = GetData(candidate, c.key).(T)
+		return ok
+	})
+	return fields, ok
+}
+
+// Definition is an error defined once with Define, which can be raised both
+// as a value (New) and as a panic (Throw), so code using either convention
+// stays in sync with the definition.
+type Definition[T any] struct {
+	*TypedClass[T]
+}
+
+// Define creates a Definition descending from parent. Messages are rendered
+// with the printf-style format: if T is a struct its fields are the
+// arguments, in order, and otherwise the value itself is. It panics if T is
+// a struct with unexported fields, which can't be arguments. The format is
+// also set as the class' MessageFormat. For example:
+//
+//	type QuotaArgs struct{ Need int }
+//
+//	var ErrQuota = errors.Define[QuotaArgs](LimitError, "Quota Error",
+//		"quota exceeded: need %d")
+//
+//	return ErrQuota.New(QuotaArgs{Need: 5})
+//	ErrQuota.Throw(QuotaArgs{Need: 5})
+//	if ErrQuota.Is(err) { ... }
+//
+// The arguments are also available from the error with Fields.
+func Define[T any](parent *ErrorClass, name, format string,
+	options ...ErrorOption) *Definition[T] {
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				panic(ProgrammerError.New("can't define %q with %s: field %s "+
+					"is unexported", name, t, t.Field(i).Name))
+			}
+		}
+	}
+	options = append([]ErrorOption{MessageFormat(format), MessageTemplate("")},
+		options...)
+	return &Definition[T]{&TypedClass[T]{
+		ErrorClass: parent.NewClass(name, options...),
+		format: func(args T) string {
+			return fmt.Sprintf(format, formatArgs(args)...)
+		},
+		key: GenSym()}}
+}
+
+// formatArgs spreads a struct's fields into printf arguments.
+func formatArgs(args interface{}) []interface{} {
+	v := reflect.ValueOf(args)
+	if v.Kind() != reflect.Struct {
+		return []interface{}{args}
+	}
+	rv := make([]interface{}, v.NumField())
+	for i := range rv {
+		rv[i] = v.Field(i).Interface()
+	}
+	return rv
+}
+
+// Throw panics with a new error of the definition, made as by New.
+func (d *Definition[T]) Throw(args T, options ...ErrorOption) {
+	panic(d.newFrom(1, args, options))
+}
+
+// Is returns whether err (or an error it wraps) belongs to the definition's
+// class.
+func (d *Definition[T]) Is(err error) bool {
+	return d.Contains(err, IncludeWrapped)
+}
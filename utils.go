@@ -19,35 +19,69 @@ import (
 	"log"
 	"runtime"
 	"strings"
+	"sync/atomic"
 )
 
 var (
-	// Change this method if you want errors to log somehow else
+	// Change this method if you want errors to log somehow else. Like
+	// Config, it isn't synchronized, so only change it during
+	// initialization; see SetLogger.
 	LogMethod = log.Printf
 
 	ErrorGroupError = NewClass("Error Group Error")
+
+	logCount uint64
 )
 
-// LogWithStack will log the given messages with the current stack
+// LogCount returns how many times this package has logged an error, through
+// LogWithStack or a LoggingErrorGroup. Comparing counts before and after a
+// block of code tells you whether it logged anything.
+func LogCount() uint64 {
+	return atomic.LoadUint64(&logCount)
+}
+
+// LogWithStack will log the given messages with the current stack. If
+// Config.Logdedupwindow is set, repeats of the same messages (or the same
+// error) within the window are suppressed, and counted in a "seen N more
+// times" note the next time they're logged.
 func LogWithStack(messages ...interface{}) {
+	atomic.AddUint64(&logCount, 1)
+	ok, suppressed := shouldLog(messages)
+	if !ok {
+		return
+	}
 	buf := make([]byte, Config.Stacklogsize)
 	buf = buf[:runtime.Stack(buf, false)]
+	if suppressed > 0 {
+		LogMethod("%s(seen %d more times)\n%s", fmt.Sprintln(messages...),
+			suppressed, buf)
+		return
+	}
 	LogMethod("%s\n%s", fmt.Sprintln(messages...), buf)
 }
 
 // CatchPanic can be used to catch panics and turn them into errors. See the
-// example.
+// example. Like try plans, it doesn't catch runtime panics that are unsafe to
+// recover from (see IsFatalRuntimePanic) but panics again with them, unless
+// Config.Catchfatal is set, in which case they become FatalRuntimeErrors.
 func CatchPanic(err_ref *error) {
 	r := recover()
 	if r == nil {
 		return
 	}
 	err, ok := r.(error)
+	if ok && IsFatalRuntimePanic(r) {
+		if !Config.Catchfatal {
+			panic(r)
+		}
+		*err_ref = RecordPanicOrigin(FatalRuntimeError.Wrap(err))
+		return
+	}
 	if ok {
-		*err_ref = PanicError.Wrap(err)
+		*err_ref = RecordPanicOrigin(PanicError.Wrap(err))
 		return
 	}
-	*err_ref = PanicError.New("%v", r)
+	*err_ref = RecordPanicOrigin(PanicError.New("%v", r))
 }
 
 // ErrorGroup is a type for collecting errors from a bunch of independent
@@ -124,6 +158,7 @@ func NewLoggingErrorGroup(name string) *LoggingErrorGroup {
 func (e *LoggingErrorGroup) Add(err error) {
 	e.total++
 	if err != nil {
+		atomic.AddUint64(&logCount, 1)
 		LogMethod("%s: %s", e.name, err)
 		e.failed++
 	}